@@ -1,15 +1,24 @@
 package tl
 
 import (
-	"fmt"
-	"hash/crc32"
+	"strconv"
+	"strings"
 	"unicode"
 	"unicode/utf8"
 )
 
-// Node	represents a node in abstract syntax tree.
+// Node	represents a node in abstract syntax tree. All node types implement the
+// Node interface.
 type Node interface {
-	node()
+	Pos() Pos // position of first character belonging to the node
+	End() Pos // position of first character immediately after the node
+}
+
+// Expr represents a type expression. All expression nodes implement the Expr
+// interface.
+type Expr interface {
+	Node
+	exprNode()
 }
 
 // Declaration represents a constructor or function declaration. All
@@ -24,111 +33,410 @@ type Declaration interface {
 type Combinator interface {
 	Node
 	Name() string
+	ComputedID() uint32
 }
 
 // Program represents a TL program.
 //
 // https://core.telegram.org/mtproto/TL-formal
 type Program struct {
-	Constructors []ConstrDecl
+	Filename string // filename given to NewFileParser, if any
+
+	Constructors []Declaration
 
 	// Optional
-	Functions []FuncDecl
-	Types     []ConstrDecl
+	Functions []Declaration
+	Types     []Declaration
+
+	FunctionsSep Pos // position of the first ---functions--- separator, if any
+	TypesSep     Pos // position of the first ---types--- separator, if any
+
+	Separators []*Separator // list of all section separators in the source
+	Comments   []*Comment   // list of all comments in the source
 }
 
-type Expr struct{}
+// Pos returns the position of the first declaration of the program.
+func (p *Program) Pos() Pos {
+	for _, decls := range [][]Declaration{p.Constructors, p.Functions, p.Types} {
+		if len(decls) > 0 {
+			return decls[0].Pos()
+		}
+	}
+	return Pos{}
+}
+
+// End returns the position immediately after the last declaration of the
+// program.
+func (p *Program) End() Pos {
+	for _, decls := range [][]Declaration{p.Types, p.Functions, p.Constructors} {
+		if n := len(decls); n > 0 {
+			return decls[n-1].End()
+		}
+	}
+	return Pos{}
+}
+
+// Separator represents a section separator, e.g. ---functions---. A
+// program may switch between sections any number of times.
+type Separator struct {
+	Start Pos    // position of the first "---"
+	Name  string // functions or types
+}
+
+// Comment represents a single //-style comment. Text does not include the
+// line terminating newline.
+type Comment struct {
+	Slash Pos // position of "/" starting the comment
+	Text  string
+}
+
+func (c *Comment) Pos() Pos { return c.Slash }
+func (c *Comment) End() Pos { return c.Slash.advance(c.Text) }
+
+// Ident represents a combinator or a field (variable) identifier.
 type Ident struct {
-	Name Token
+	NamePos Pos
+	Name    Token
 }
 
-func (i Ident) Text() string {
+func (i *Ident) Text() string {
 	return i.Name.Literal
 }
 
+// CombinatorId represents a combinator-id.
 type CombinatorId struct {
-	Id Ident
+	Id *Ident
 }
 
+// FullCombinatorId represents a full-combinator-id, a combinator-id with an
+// optional explicit combinator-name, e.g. user#d23c81a3.
 type FullCombinatorId struct {
-	Id Ident
+	Id *Ident
 }
 
-type Arg struct{}
-type OptionalArg struct{}
+// Name returns the combinator-id without the combinator-name, e.g. user for
+// user#d23c81a3.
+func (c *FullCombinatorId) Name() string {
+	name := c.Id.Text()
+	if i := strings.IndexByte(name, '#'); i >= 0 {
+		return name[:i]
+	}
+	return name
+}
+
+// ID returns the explicit combinator-name, if any, e.g. 0xd23c81a3 for
+// user#d23c81a3.
+func (c *FullCombinatorId) ID() (id uint32, ok bool) {
+	name := c.Id.Text()
+	i := strings.IndexByte(name, '#')
+	if i < 0 {
+		return 0, false
+	}
 
-type TypeIdent struct {
-	Name string
+	v, err := strconv.ParseUint(name[i+1:], 16, 32)
+	if err != nil {
+		return 0, false
+	}
+	return uint32(v), true
 }
+
+// An expression is represented by a tree consisting of one or more of the
+// following concrete expression nodes.
+//
+type (
+	// TypeIdent represents a type-ident: boxed-type-ident, lc-ident-ns or '#'.
+	TypeIdent struct {
+		NamePos Pos
+		Name    string
+	}
+
+	// Var represents a reference to a field (variable) declared earlier in
+	// the enclosing combinator, e.g. the t in `vector {t:Type} # [t] = Vector t`.
+	Var struct {
+		NamePos Pos
+		Name    string
+	}
+
+	// NatConst represents a nat-const.
+	NatConst struct {
+		ValuePos Pos
+		Value    string
+	}
+
+	// BareType represents a bare type marker, e.g. %(Vector t).
+	BareType struct {
+		Percent Pos
+		X       Expr
+	}
+
+	// ParenExpr represents a parenthesized expression, e.g. (Vector int).
+	ParenExpr struct {
+		Lparen Pos
+		X      Expr
+		Rparen Pos
+	}
+
+	// AppExpr represents a type application, either in the form of
+	// `Vector t` or `Vector<t>`. Lang and Rang are only valid for the
+	// latter.
+	AppExpr struct {
+		Fun  Expr
+		Lang Pos
+		Args []Expr
+		Rang Pos
+	}
+
+	// SumExpr represents a nat expression, e.g. n+1.
+	SumExpr struct {
+		X    Expr
+		Plus Pos
+		Y    Expr
+	}
+
+	// Repetition represents repeated arguments, optionally preceded by a
+	// multiplicity, e.g. n*[x:int y:int].
+	Repetition struct {
+		Mult   Expr // multiplicity; or nil
+		Star   Pos  // position of '*', if any
+		Lbrack Pos
+		Args   []*Arg
+		Rbrack Pos
+	}
+)
+
+// Pos and End implementations for expression nodes.
+//
+func (x *TypeIdent) Pos() Pos { return x.NamePos }
+func (x *Var) Pos() Pos       { return x.NamePos }
+func (x *NatConst) Pos() Pos  { return x.ValuePos }
+func (x *BareType) Pos() Pos  { return x.Percent }
+func (x *ParenExpr) Pos() Pos { return x.Lparen }
+func (x *AppExpr) Pos() Pos   { return x.Fun.Pos() }
+func (x *SumExpr) Pos() Pos   { return x.X.Pos() }
+func (x *Repetition) Pos() Pos {
+	if x.Mult != nil {
+		return x.Mult.Pos()
+	}
+	return x.Lbrack
+}
+
+func (x *TypeIdent) End() Pos { return x.NamePos.advance(x.Name) }
+func (x *Var) End() Pos       { return x.NamePos.advance(x.Name) }
+func (x *NatConst) End() Pos  { return x.ValuePos.advance(x.Value) }
+func (x *BareType) End() Pos  { return x.X.End() }
+func (x *ParenExpr) End() Pos { return x.Rparen.advance(")") }
+func (x *AppExpr) End() Pos {
+	if x.Rang.IsValid() {
+		return x.Rang.advance(">")
+	}
+	return x.Args[len(x.Args)-1].End()
+}
+func (x *SumExpr) End() Pos    { return x.Y.End() }
+func (x *Repetition) End() Pos { return x.Rbrack.advance("]") }
+
+// exprNode() ensures that only expression nodes can be assigned to an Expr.
+//
+func (*TypeIdent) exprNode()  {}
+func (*Var) exprNode()        {}
+func (*NatConst) exprNode()   {}
+func (*BareType) exprNode()   {}
+func (*ParenExpr) exprNode()  {}
+func (*AppExpr) exprNode()    {}
+func (*SumExpr) exprNode()    {}
+func (*Repetition) exprNode() {}
+
+// Arg represents a required argument (field) of a combinator in one of the
+// following forms:
+//
+//   id:int                 Names: [id], Type: int
+//   x:flags.3?int          Names: [x], Cond: flags.3, Type: int
+//   query:!X               Names: [query], Excl: valid, Type: X
+//   (a b:int)              Lparen, Names: [a b], Type: int, Rparen
+//   int                    Names: nil, Type: int
+//   n*[x:int]              Names: nil, Type: Repetition
+//
+type Arg struct {
+	Lparen Pos      // position of '(', if any
+	Names  []*Ident // field names; or nil
+	Colon  Pos      // position of ':', if any
+	Cond   *CondDef // conditional-arg-def; or nil
+	Excl   Pos      // position of '!', if any
+	Type   Expr
+	Rparen Pos // position of ')', if any
+}
+
+// OptionalArg represents optional arguments of a combinator, e.g. {t:Type}.
+type OptionalArg struct {
+	Lbrace Pos
+	Names  []*Ident
+	Colon  Pos
+	Excl   Pos // position of '!', if any
+	Type   Expr
+	Rbrace Pos
+}
+
+// CondDef represents a conditional-arg-def, e.g. the flags.3? in
+// `x:flags.3?int`.
+type CondDef struct {
+	Field *Ident
+	Dot   Pos       // position of '.', if any
+	Bit   *NatConst // or nil
+	Quest Pos
+}
+
+// ResultType represents a combinator's result type, e.g. Vector t or
+// Vector<t>. Lang and Rang are only valid for the latter.
+type ResultType struct {
+	Name *BoxedTypeIdent
+	Lang Pos
+	Args []Expr
+	Rang Pos
+}
+
+// BoxedTypeIdent represents a boxed-type-ident.
 type BoxedTypeIdent struct {
-	Name string
+	NamePos Pos
+	Name    string
 }
-type BareType struct{}
-type ResultType struct{}
+
+func (i *Ident) Pos() Pos            { return i.NamePos }
+func (c *CombinatorId) Pos() Pos     { return c.Id.Pos() }
+func (c *FullCombinatorId) Pos() Pos { return c.Id.Pos() }
+func (a *Arg) Pos() Pos {
+	switch {
+	case a.Lparen.IsValid():
+		return a.Lparen
+	case len(a.Names) > 0:
+		return a.Names[0].Pos()
+	case a.Excl.IsValid():
+		return a.Excl
+	}
+	return a.Type.Pos()
+}
+func (a *OptionalArg) Pos() Pos    { return a.Lbrace }
+func (c *CondDef) Pos() Pos        { return c.Field.Pos() }
+func (r *ResultType) Pos() Pos     { return r.Name.Pos() }
+func (b *BoxedTypeIdent) Pos() Pos { return b.NamePos }
+
+func (i *Ident) End() Pos            { return i.NamePos.advance(i.Name.Literal) }
+func (c *CombinatorId) End() Pos     { return c.Id.End() }
+func (c *FullCombinatorId) End() Pos { return c.Id.End() }
+func (a *Arg) End() Pos {
+	if a.Rparen.IsValid() {
+		return a.Rparen.advance(")")
+	}
+	return a.Type.End()
+}
+func (a *OptionalArg) End() Pos { return a.Rbrace.advance("}") }
+func (c *CondDef) End() Pos     { return c.Quest.advance("?") }
+func (r *ResultType) End() Pos {
+	switch {
+	case r.Rang.IsValid():
+		return r.Rang.advance(">")
+	case len(r.Args) > 0:
+		return r.Args[len(r.Args)-1].End()
+	}
+	return r.Name.End()
+}
+func (b *BoxedTypeIdent) End() Pos { return b.NamePos.advance(b.Name) }
 
 // A declaration is represented by one of the following declaration nodes.
 //
 type (
+	// CombDecl represents a combinator-decl.
+	//
+	// user#d23c81a3 id:int first_name:string last_name:string = User;
 	CombDecl struct {
-		Id      FullCombinatorId
-		OptArgs []OptionalArg
-		Args    []Arg
-		Result  ResultType
+		Id      *FullCombinatorId
+		OptArgs []*OptionalArg
+		Args    []*Arg
+		Equals  Pos
+		Result  *ResultType
 	}
 
+	// BuiltinCombDecl represents a builtin-combinator-decl.
+	//
+	// int ? = Int;
 	BuiltinCombDecl struct {
-		Id     FullCombinatorId
-		Result BoxedTypeIdent
+		Id     *FullCombinatorId
+		Quest  Pos
+		Result *BoxedTypeIdent
+	}
+
+	// PartialTypeAppDecl represents a partial-type-app-decl. Lang and Rang
+	// are only valid for the angle bracket form.
+	//
+	// Vector int; | Pair<Int,String>;
+	PartialTypeAppDecl struct {
+		Name *BoxedTypeIdent
+		Lang Pos
+		Args []Expr
+		Rang Pos
+	}
+
+	// PartialCombAppDecl represents a partial-comb-app-decl.
+	//
+	// vector int;
+	PartialCombAppDecl struct {
+		Id   *CombinatorId
+		Args []Expr
 	}
 
-	ConstrDecl struct{}
-	FuncDecl   struct{}
+	// FinalDecl represents a final-decl. Kind is one of ItemNew, ItemFinal
+	// or ItemEmpty.
+	//
+	// Empty False;
+	FinalDecl struct {
+		KindPos Pos
+		Kind    Item
+		Name    *BoxedTypeIdent
+	}
 )
 
-// node implementations for declaration nodes.
+// Pos and End implementations for declaration nodes.
 //
-func (ConstrDecl) node()      {}
-func (FuncDecl) node()        {}
-func (CombDecl) node()        {}
-func (BuiltinCombDecl) node() {}
+func (d *CombDecl) Pos() Pos           { return d.Id.Pos() }
+func (d *BuiltinCombDecl) Pos() Pos    { return d.Id.Pos() }
+func (d *PartialTypeAppDecl) Pos() Pos { return d.Name.Pos() }
+func (d *PartialCombAppDecl) Pos() Pos { return d.Id.Pos() }
+func (d *FinalDecl) Pos() Pos          { return d.KindPos }
+
+func (d *CombDecl) End() Pos        { return d.Result.End() }
+func (d *BuiltinCombDecl) End() Pos { return d.Result.End() }
+func (d *PartialTypeAppDecl) End() Pos {
+	if d.Rang.IsValid() {
+		return d.Rang.advance(">")
+	}
+	return d.Args[len(d.Args)-1].End()
+}
+func (d *PartialCombAppDecl) End() Pos { return d.Args[len(d.Args)-1].End() }
+func (d *FinalDecl) End() Pos          { return d.Name.End() }
 
 // declNode() ensures that only declaration nodes can be assigned to a declaration node.
 //
-func (ConstrDecl) declNode()      {}
-func (FuncDecl) declNode()        {}
-func (CombDecl) declNode()        {}
-func (BuiltinCombDecl) declNode() {}
+func (*CombDecl) declNode()           {}
+func (*BuiltinCombDecl) declNode()    {}
+func (*PartialTypeAppDecl) declNode() {}
+func (*PartialCombAppDecl) declNode() {}
+func (*FinalDecl) declNode()          {}
 
-// combinatorNode implementations for combinator nodes.
+// Name implementations for combinator nodes.
 //
-func (ConstrDecl) Name() {}
-func (FuncDecl) Name()   {}
-func (CombDecl) Name()   {}
+func (d *CombDecl) Name() string        { return d.Id.Name() }
+func (d *BuiltinCombDecl) Name() string { return d.Id.Name() }
 
 //
 // Constructors
 //
 
-func NewIdent(name string) Ident {
+func NewIdent(name string) *Ident {
 	ch, _ := utf8.DecodeRuneInString(name)
 
 	if name == "_" {
-		return Ident{Name: Token{Token: ItemUnderscore, Literal: name}}
+		return &Ident{Name: Token{Token: ItemUnderscore, Literal: name}}
 	} else if unicode.IsLower(ch) {
-		return Ident{Name: Token{Token: ItemLowerIdent, Literal: name}}
+		return &Ident{Name: Token{Token: ItemLowerIdent, Literal: name}}
 	} else {
-		return Ident{Name: Token{Token: ItemUpperIdent, Literal: name}}
+		return &Ident{Name: Token{Token: ItemUpperIdent, Literal: name}}
 	}
 }
-
-// computeCRC32 calculates the combinator-name for the given combinator-description.
-// e.g. int128 ? = Int128 => a8509bda
-func computeCRC32(s string) (string, error) {
-	h := crc32.NewIEEE()
-	_, err := h.Write([]byte(s))
-	if err != nil {
-		return "", err
-	}
-
-	return fmt.Sprintf("%x", h.Sum32()), nil
-}
@@ -0,0 +1,66 @@
+package tl
+
+import "fmt"
+
+// Boxing describes how a value of a type is serialized. Values of boxed types
+// are preceded by the combinator-name of their constructor, values of bare
+// types are not.
+type Boxing int
+
+const (
+	Boxed           Boxing = iota // e.g. InputPeer, Vector<int>, t
+	BarePercent                   // boxed type made bare with %, e.g. %(Vector t)
+	BareConstructor               // named after its constructor, e.g. int, inputPhotoEmpty
+	BareImplicit                  // # and repetitions, which have no constructors
+)
+
+var boxings = [...]string{
+	Boxed:           "boxed",
+	BarePercent:     "bare by %",
+	BareConstructor: "bare by constructor name",
+	BareImplicit:    "bare",
+}
+
+func (b Boxing) String() string {
+	if 0 <= b && int(b) < len(boxings) {
+		return boxings[b]
+	}
+	return fmt.Sprintf("Boxing(%d)", int(b))
+}
+
+// Bare reports whether the type is serialized without a constructor id.
+func (t *TypeRef) Bare() bool { return t.Boxing != Boxed }
+
+// bare checks that the type x made bare with % has exactly one constructor.
+// The constructor of a bare type must be known in advance, since its
+// combinator-name is not serialized.
+func (r *resolver) bare(x *BareType) {
+	ident := typeHead(x.X)
+	if ident == nil {
+		return
+	}
+
+	t, ok := r.s.Uses[ident].(*Type)
+	if !ok || len(t.Constructors) == 1 {
+		return
+	}
+	r.errs.Add(x.Pos(), fmt.Sprintf("cannot use %%%s: %s has %d constructors, expected 1",
+		ident.Name, t.name, len(t.Constructors)))
+}
+
+// typeHead returns the identifier of the type x refers to, e.g. Vector for
+// (Vector t), or nil.
+func typeHead(x Expr) *TypeIdent {
+	for {
+		switch y := x.(type) {
+		case *TypeIdent:
+			return y
+		case *ParenExpr:
+			x = y.X
+		case *AppExpr:
+			x = y.Fun
+		default:
+			return nil
+		}
+	}
+}
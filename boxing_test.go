@@ -0,0 +1,58 @@
+package tl
+
+import "testing"
+
+func TestTypeRef_Boxing(t *testing.T) {
+	const src = `
+vector#1cb5c415 {t:Type} # [ t ] = Vector t;
+vectorTotal {t:Type} total_count:int vector:%(Vector t) = VectorTotal t;
+inputPeerEmpty#7f3b18ea = InputPeer;
+inputPeerSelf#7da07ec9 = InputPeer;
+peers n:# list:n*[InputPeer] empty:inputPeerEmpty id:int self:%Vector<InputPeer> = Peers;
+`
+
+	schema, err := NewSchema(parseString(t, src))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	vt := schema.Constructor("vectorTotal")
+	if b := vt.Fields[2].Type.Boxing; b != BarePercent {
+		t.Errorf("%%(Vector t) is %v, expected %v", b, BarePercent)
+	}
+
+	peers := schema.Constructor("peers")
+	tests := []struct {
+		field  string
+		boxing Boxing
+	}{
+		{"n", BareImplicit},
+		{"list", BareImplicit},
+		{"empty", BareConstructor},
+		{"id", BareConstructor},
+		{"self", BarePercent},
+	}
+	for i, tt := range tests {
+		f := peers.Fields[i]
+		if f.Name != tt.field || f.Type.Boxing != tt.boxing {
+			t.Errorf("<%d> %s is %v, expected %s to be %v", i, f.Name, f.Type.Boxing, tt.field, tt.boxing)
+		}
+	}
+	if elem := peers.Fields[1].Type.Fields[0].Type; elem.Boxing != Boxed || elem.Bare() {
+		t.Errorf("InputPeer in repetition is %v, expected boxed", elem.Boxing)
+	}
+}
+
+func TestSchema_bareMultipleConstructors(t *testing.T) {
+	const src = `
+inputPeerEmpty#7f3b18ea = InputPeer;
+inputPeerSelf#7da07ec9 = InputPeer;
+peer p:%InputPeer = Peer;
+`
+
+	_, err := NewSchema(parseString(t, src))
+	want := "4:8: cannot use %InputPeer: InputPeer has 2 constructors, expected 1"
+	if err == nil || err.Error() != want {
+		t.Errorf("got error %v, expected %q", err, want)
+	}
+}
@@ -0,0 +1,126 @@
+package tl
+
+import (
+	"sort"
+	"strings"
+)
+
+// ErrorList is a list of *Errors.
+type ErrorList []*Error
+
+// Add adds an Error with given position and error message to an ErrorList.
+func (l *ErrorList) Add(pos Pos, msg string) {
+	*l = append(*l, &Error{Pos: pos, Msg: msg})
+}
+
+// AddSoft adds a soft Error (warning) with given position and error message to
+// an ErrorList.
+func (l *ErrorList) AddSoft(pos Pos, msg string) {
+	*l = append(*l, &Error{Pos: pos, Msg: msg, Soft: true})
+}
+
+// Sort sorts an ErrorList by filename and position. Errors with the same
+// position keep their order.
+func (l ErrorList) Sort() {
+	l.SortFiles(nil)
+}
+
+// SortFiles sorts an ErrorList by the index of the filename of the errors in
+// files, e.g. the files in the order given on the command line, then by
+// position. Errors of other files come last, sorted by filename. Errors with
+// the same position keep their order.
+func (l ErrorList) SortFiles(files []string) {
+	order := FileOrder(files)
+	sort.SliceStable(l, func(i, j int) bool {
+		return order(l[i].Pos, l[j].Pos)
+	})
+}
+
+// FileOrder returns a function reporting whether position p sorts before q
+// in the order of SortFiles: by the index of their filename in files, with
+// other files last by filename, then by offset.
+func FileOrder(files []string) func(p, q Pos) bool {
+	index := make(map[string]int, len(files))
+	for i, name := range files {
+		if _, ok := index[name]; !ok {
+			index[name] = i
+		}
+	}
+	rank := func(name string) int {
+		if i, ok := index[name]; ok {
+			return i
+		}
+		return len(files)
+	}
+
+	return func(p, q Pos) bool {
+		if p.Filename != q.Filename {
+			if rp, rq := rank(p.Filename), rank(q.Filename); rp != rq {
+				return rp < rq
+			}
+			return p.Filename < q.Filename
+		}
+		return p.Offset < q.Offset
+	}
+}
+
+// Filenames returns the filenames of the given programs in order.
+func Filenames(progs ...*Program) []string {
+	files := make([]string, len(progs))
+	for i, prog := range progs {
+		files[i] = prog.Filename
+	}
+	return files
+}
+
+// An ErrorList implements the error interface.
+func (l ErrorList) Error() string {
+	msgs := make([]string, len(l))
+	for i, err := range l {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// Err returns an error equivalent to this error list. If the list contains no
+// errors other than soft errors, Err returns nil.
+func (l ErrorList) Err() error {
+	for _, err := range l {
+		if !err.Soft {
+			return l
+		}
+	}
+	return nil
+}
+
+// A Checker reports semantic errors of a parsed TL program.
+type Checker struct {
+	// SoftIDMismatch reports explicit combinator-names that differ from the
+	// computed ones as soft errors (warnings) instead of errors.
+	SoftIDMismatch bool
+}
+
+// Check checks the given programs, the files of a single schema, and returns
+// the errors found, sorted by position.
+func (c *Checker) Check(progs ...*Program) ErrorList {
+	var errs ErrorList
+
+	for _, prog := range progs {
+		for _, m := range VerifyIDs(prog) {
+			errs = append(errs, &Error{Pos: m.Decl.Pos(), Msg: m.message(), Soft: c.SoftIDMismatch})
+		}
+	}
+
+	// redeclarations and undefined types
+	s, err := NewSchema(progs...)
+	if err != nil {
+		errs = append(errs, err.(ErrorList)...)
+	}
+	s.checkConflicts(&errs)
+	s.typecheck(&errs)
+	s.checkFlags(&errs)
+	s.checkWellFounded(&errs)
+
+	errs.SortFiles(Filenames(progs...))
+	return errs
+}
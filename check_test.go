@@ -0,0 +1,145 @@
+package tl
+
+import (
+	"bytes"
+	"os"
+	"testing"
+)
+
+func parseString(t *testing.T, src string) *Program {
+	parser := NewParser(bytes.NewBufferString(src))
+	program := parser.Parse()
+	if err := parser.Err(); err != nil {
+		t.Fatalf("parsing %q: %v", src, err)
+	}
+	return program
+}
+
+func TestVerifyIDs(t *testing.T) {
+	const src = `
+int#a8509bda ? = Int;
+long#deadbeef ? = Long;
+user#d23c81a3 id:int first_name:string last_name:string = User;
+group#d23c81a3 id:int title:string = Group;
+chat id:int = Chat;
+---functions---
+getUsers#2d84d5f5 (Vector int) = Vector User;
+getUser#2d84d5f5 int = User;
+`
+
+	mismatches := VerifyIDs(parseString(t, src))
+
+	var tests = []struct {
+		name     string
+		line     int
+		explicit uint32
+		desc     string
+	}{
+		{"long", 3, 0xdeadbeef, "long ? = Long"},
+		{"group", 5, 0xd23c81a3, "group id:int title:string = Group"},
+		{"getUser", 9, 0x2d84d5f5, "getUser int = User"},
+	}
+
+	if len(mismatches) != len(tests) {
+		t.Fatalf("got %d mismatches, expected %d: %v", len(mismatches), len(tests), mismatches)
+	}
+
+	for i, tt := range tests {
+		m := mismatches[i]
+		if m.Decl.Name() != tt.name || m.Decl.Pos().Line != tt.line {
+			t.Errorf("<%d> got mismatch for %v at line %d, expected %v at line %d", i, m.Decl.Name(), m.Decl.Pos().Line, tt.name, tt.line)
+		}
+		if m.Explicit != tt.explicit {
+			t.Errorf("<%d> bad explicit id: got %08x, expected %08x", i, m.Explicit, tt.explicit)
+		}
+		if m.Description != tt.desc {
+			t.Errorf("<%d> bad description: got %q, expected %q", i, m.Description, tt.desc)
+		}
+		if m.Computed != computeCRC32(tt.desc) {
+			t.Errorf("<%d> bad computed id: got %08x, expected %08x", i, m.Computed, computeCRC32(tt.desc))
+		}
+	}
+}
+
+func TestVerifyIDs_schema(t *testing.T) {
+	f, err := os.Open("schema.tl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	parser := NewParser(f)
+	program := parser.Parse()
+	if parser.Err() != nil {
+		t.Fatal(parser.Err())
+	}
+
+	// document_l19 is a legacy constructor whose id is not computed from its
+	// description.
+	mismatches := VerifyIDs(program)
+	if len(mismatches) != 1 || mismatches[0].Decl.Name() != "document_l19" {
+		t.Errorf("got mismatches %v, expected only document_l19", mismatches)
+	}
+}
+
+func TestChecker_SoftIDMismatch(t *testing.T) {
+	program := parseString(t, "user#d23c81a3 id:int = User;")
+
+	errs := (&Checker{}).Check(program)
+	if len(errs) != 1 || errs.Err() == nil {
+		t.Fatalf("expected an id mismatch error, got %v", errs)
+	}
+	if errs[0].Pos != (Pos{Offset: 0, Line: 1, Column: 1}) {
+		t.Errorf("bad error position: %v", errs[0].Pos)
+	}
+
+	errs = (&Checker{SoftIDMismatch: true}).Check(program)
+	if len(errs) != 1 || !errs[0].Soft {
+		t.Fatalf("expected an id mismatch warning, got %v", errs)
+	}
+	if errs.Err() != nil {
+		t.Errorf("soft errors must not fail the check: %v", errs.Err())
+	}
+}
+
+// TestChecker_fileOrder checks that errors are sorted by the order of the
+// files given, not by filename.
+func TestChecker_fileOrder(t *testing.T) {
+	progs := []*Program{
+		parseFileString(t, "b.tl", "user foo:Foo = User;"),
+		parseFileString(t, "a.tl", "chat bar:Bar = Chat;"),
+	}
+
+	var got []string
+	for _, err := range (&Checker{SoftIDMismatch: true}).Check(progs...) {
+		if !err.Soft {
+			got = append(got, err.Error())
+		}
+	}
+	want := []string{"b.tl:1:10: undefined: Foo", "a.tl:1:10: undefined: Bar"}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("got errors %q, expected %q", got, want)
+	}
+}
+
+func TestFileOrder(t *testing.T) {
+	less := FileOrder([]string{"b.tl", "a.tl"})
+
+	var tests = []struct {
+		p, q Pos
+		want bool
+	}{
+		{Pos{Filename: "b.tl", Offset: 9}, Pos{Filename: "a.tl", Offset: 0}, true},
+		{Pos{Filename: "a.tl", Offset: 0}, Pos{Filename: "b.tl", Offset: 9}, false},
+		{Pos{Filename: "a.tl", Offset: 1}, Pos{Filename: "a.tl", Offset: 2}, true},
+		{Pos{Filename: "a.tl", Offset: 9}, Pos{Filename: "0.tl", Offset: 0}, true},
+		{Pos{Filename: "0.tl", Offset: 0}, Pos{Filename: "b.tl", Offset: 0}, false},
+		{Pos{Filename: "0.tl", Offset: 0}, Pos{Filename: "1.tl", Offset: 0}, true},
+	}
+
+	for i, tt := range tests {
+		if got := less(tt.p, tt.q); got != tt.want {
+			t.Errorf("<%d> FileOrder(%v, %v): got %v, expected %v", i, tt.p, tt.q, got, tt.want)
+		}
+	}
+}
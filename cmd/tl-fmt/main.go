@@ -0,0 +1,171 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"os/exec"
+
+	"github.com/igungor/tl"
+	"github.com/igungor/tl/printer"
+)
+
+var (
+	list   = flag.Bool("l", false, "list files whose formatting differs from tl-fmt's")
+	write  = flag.Bool("w", false, "write result to (source) file instead of stdout")
+	doDiff = flag.Bool("d", false, "display diffs instead of rewriting files")
+	style  = flag.String("style", "keep", "type application style: keep, angle (Vector<T>) or space (Vector T)")
+)
+
+var styles = map[string]printer.Style{
+	"keep":  printer.KeepStyle,
+	"angle": printer.AngleStyle,
+	"space": printer.SpaceStyle,
+}
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: tl-fmt [flags] [path ...]\n")
+		flag.PrintDefaults()
+		os.Exit(2)
+	}
+
+	flag.Parse()
+	log.SetFlags(0)
+	log.SetPrefix("tl-fmt: ")
+
+	s, ok := styles[*style]
+	if !ok {
+		flag.Usage()
+	}
+	cfg := &printer.Config{Style: s}
+
+	if flag.NArg() == 0 {
+		if *write {
+			log.Fatal("cannot use -w with standard input")
+		}
+		if err := processFile(cfg, "<standard input>", os.Stdin, os.Stdout); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	var failed bool
+	for _, path := range flag.Args() {
+		if err := processFile(cfg, path, nil, os.Stdout); err != nil {
+			log.Print(err)
+			failed = true
+		}
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
+
+// processFile formats the given file. If in is nil, the file is read from
+// filename.
+func processFile(cfg *printer.Config, filename string, in io.Reader, out io.Writer) error {
+	if in == nil {
+		f, err := os.Open(filename)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = f
+	}
+
+	src, err := ioutil.ReadAll(in)
+	if err != nil {
+		return err
+	}
+
+	parser := tl.NewFileParser(filename, bytes.NewReader(src))
+	program := parser.Parse()
+	if err := parser.Err(); err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	if err := cfg.Fprint(&buf, program); err != nil {
+		return err
+	}
+	res := buf.Bytes()
+
+	if bytes.Equal(src, res) {
+		if !*list && !*write && !*doDiff {
+			_, err = out.Write(res)
+		}
+		return err
+	}
+
+	if *list {
+		fmt.Fprintln(out, filename)
+	}
+	if *write {
+		fi, err := os.Stat(filename)
+		if err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(filename, res, fi.Mode().Perm()); err != nil {
+			return err
+		}
+	}
+	if *doDiff {
+		data, err := diff(src, res)
+		if err != nil {
+			return fmt.Errorf("computing diff: %v", err)
+		}
+		fmt.Fprintf(out, "diff %s tl-fmt/%s\n", filename, filename)
+		out.Write(data)
+	}
+
+	if !*list && !*write && !*doDiff {
+		_, err = out.Write(res)
+	}
+
+	return err
+}
+
+// diff returns the unified diff of the given contents using the system's diff
+// tool.
+func diff(b1, b2 []byte) ([]byte, error) {
+	f1, err := writeTempFile("tl-fmt", b1)
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(f1)
+
+	f2, err := writeTempFile("tl-fmt", b2)
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(f2)
+
+	data, err := exec.Command("diff", "-u", f1, f2).CombinedOutput()
+	if len(data) > 0 {
+		// diff exits with a non-zero status when the files don't match.
+		// Ignore that failure as long as we get output.
+		err = nil
+	}
+	return data, err
+}
+
+func writeTempFile(prefix string, data []byte) (string, error) {
+	file, err := ioutil.TempFile("", prefix)
+	if err != nil {
+		return "", err
+	}
+	_, err = file.Write(data)
+	if err1 := file.Close(); err == nil {
+		err = err1
+	}
+	if err != nil {
+		os.Remove(file.Name())
+		return "", err
+	}
+	return file.Name(), nil
+}
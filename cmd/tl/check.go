@@ -0,0 +1,49 @@
+package main
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/igungor/tl"
+)
+
+var cmdCheck = &command{
+	UsageLine: "check [-soft-ids] file.tl ...",
+	Short:     "report semantic errors of a TL schema",
+}
+
+var checkSoftIDs = cmdCheck.Flag.Bool("soft-ids", false, "report explicit ids that differ from the computed ones as warnings")
+
+func init() {
+	cmdCheck.Run = runCheck
+}
+
+// runCheck checks the given files as the files of a single schema, in order.
+func runCheck(cmd *command, args []string) {
+	if len(args) == 0 {
+		cmd.Usage()
+	}
+
+	var programs []*tl.Program
+	for _, filename := range args {
+		program, err := parseFile(filename)
+		if err != nil {
+			log.Print(err)
+			setExitStatus(1)
+			continue
+		}
+		programs = append(programs, program)
+	}
+	if exitStatus != 0 {
+		return
+	}
+
+	checker := &tl.Checker{SoftIDMismatch: *checkSoftIDs}
+	errs := checker.Check(programs...)
+	for _, err := range errs {
+		fmt.Println(err)
+	}
+	if errs.Err() != nil {
+		setExitStatus(1)
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/igungor/tl/compat"
+)
+
+var cmdCompat = &command{
+	UsageLine: "compat [-json] [-v] old.tl new.tl",
+	Short:     "report the changes between two TL schemas breaking old peers",
+}
+
+var (
+	compatJSON    = cmdCompat.Flag.Bool("json", false, "print the results as JSON")
+	compatVerbose = cmdCompat.Flag.Bool("v", false, "print the compatible changes as well")
+)
+
+func init() {
+	cmdCompat.Run = runCompat
+}
+
+// runCompat prints the breaking changes from the old schema to the new one.
+// It exits with status 1 if there are any.
+func runCompat(cmd *command, args []string) {
+	if len(args) != 2 {
+		cmd.Usage()
+	}
+
+	old, err := loadSchema(args[0])
+	if err != nil {
+		log.Fatal(err)
+	}
+	new, err := loadSchema(args[1])
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	results := compat.Check(old, new)
+	if !*compatVerbose {
+		var breaking []*compat.Result
+		for _, r := range results {
+			if r.Breaking() {
+				breaking = append(breaking, r)
+			}
+		}
+		results = breaking
+	}
+
+	if *compatJSON {
+		if results == nil {
+			results = []*compat.Result{}
+		}
+		data, err := json.Marshal(results)
+		if err != nil {
+			log.Fatal(err)
+		}
+		os.Stdout.Write(data)
+		fmt.Println()
+	} else {
+		for _, r := range results {
+			fmt.Println(r)
+		}
+	}
+
+	if compat.Breaking(results) {
+		setExitStatus(1)
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"encoding/hex"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/igungor/tl/tlbin"
+)
+
+var cmdDecode = &command{
+	UsageLine: "decode [-hex] [-untagged] [-result function] file.tl [data]",
+	Short:     "print a serialized value as JSON",
+}
+
+var (
+	decodeHex      = cmdDecode.Flag.Bool("hex", false, "read the serialization in hex, spaces ignored")
+	decodeResult   = cmdDecode.Flag.String("result", "", "decode a result of the given `function` instead of a boxed object")
+	decodeUntagged = cmdDecode.Flag.Bool("untagged", false, `omit the "_" members naming the constructors`)
+)
+
+func init() {
+	cmdDecode.Run = runDecode
+}
+
+// runDecode prints the JSON form of the value serialized in the given file,
+// or the standard input.
+func runDecode(cmd *command, args []string) {
+	if len(args) != 1 && len(args) != 2 {
+		cmd.Usage()
+	}
+
+	schema, err := loadSchema(args[0])
+	if err != nil {
+		log.Fatal(err)
+	}
+	data, err := readInput(args[1:])
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *decodeHex {
+		data, err = hex.DecodeString(strings.Join(strings.Fields(string(data)), ""))
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	var v tlbin.Value
+	if *decodeResult != "" {
+		v, err = tlbin.UnmarshalResult(schema, lookupFunction(schema, *decodeResult), data)
+	} else {
+		v, err = tlbin.Unmarshal(schema, data)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	out, err := (&tlbin.JSONConfig{Untagged: *decodeUntagged}).JSON(v)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(out))
+}
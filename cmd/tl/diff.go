@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/igungor/tl/diff"
+)
+
+var cmdDiff = &command{
+	UsageLine: "diff [-json] old.tl new.tl",
+	Short:     "report the changes between two TL schemas",
+}
+
+var diffJSON = cmdDiff.Flag.Bool("json", false, "print the changes as JSON")
+
+func init() {
+	cmdDiff.Run = runDiff
+}
+
+// runDiff prints the changes from the old schema to the new one. Like diff,
+// it exits with status 1 if there are changes.
+func runDiff(cmd *command, args []string) {
+	if len(args) != 2 {
+		cmd.Usage()
+	}
+
+	old, err := loadSchema(args[0])
+	if err != nil {
+		log.Fatal(err)
+	}
+	new, err := loadSchema(args[1])
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	changes := diff.Schemas(old, new)
+
+	if *diffJSON {
+		data, err := diff.JSON(changes)
+		if err != nil {
+			log.Fatal(err)
+		}
+		os.Stdout.Write(data)
+		fmt.Println()
+	} else {
+		for _, c := range changes {
+			fmt.Println(c)
+		}
+	}
+
+	if len(changes) > 0 {
+		setExitStatus(1)
+	}
+}
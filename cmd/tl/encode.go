@@ -0,0 +1,90 @@
+package main
+
+import (
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/igungor/tl"
+	"github.com/igungor/tl/tlbin"
+)
+
+var cmdEncode = &command{
+	UsageLine: "encode [-hex] [-result function] file.tl [value.json]",
+	Short:     "serialize a value given in JSON",
+}
+
+var (
+	encodeHex    = cmdEncode.Flag.Bool("hex", false, "print the serialization in hex")
+	encodeResult = cmdEncode.Flag.String("result", "", "encode a result of the given `function` instead of a boxed object")
+)
+
+func init() {
+	cmdEncode.Run = runEncode
+}
+
+// runEncode writes the serialization of the JSON value read from the given
+// file, or the standard input, to the standard output.
+func runEncode(cmd *command, args []string) {
+	if len(args) != 1 && len(args) != 2 {
+		cmd.Usage()
+	}
+
+	schema, err := loadSchema(args[0])
+	if err != nil {
+		log.Fatal(err)
+	}
+	in, err := readInput(args[1:])
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var data []byte
+	if *encodeResult != "" {
+		f := lookupFunction(schema, *encodeResult)
+		v, err := tlbin.ResultFromJSON(schema, f, in)
+		if err != nil {
+			log.Fatal(err)
+		}
+		data, err = tlbin.MarshalResult(schema, f, v)
+		if err != nil {
+			log.Fatal(err)
+		}
+	} else {
+		v, err := tlbin.FromJSON(schema, in)
+		if err != nil {
+			log.Fatal(err)
+		}
+		data, err = tlbin.Marshal(schema, v)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	if *encodeHex {
+		fmt.Println(hex.EncodeToString(data))
+		return
+	}
+	os.Stdout.Write(data)
+}
+
+// readInput returns the contents of the file named by args, or of the
+// standard input if there is none.
+func readInput(args []string) ([]byte, error) {
+	if len(args) == 0 {
+		return io.ReadAll(os.Stdin)
+	}
+	return os.ReadFile(args[0])
+}
+
+// lookupFunction returns the function of the schema with the given name, or
+// exits.
+func lookupFunction(schema *tl.Schema, name string) *tl.Function {
+	f := schema.Function(name)
+	if f == nil {
+		log.Fatalf("unknown function %s", name)
+	}
+	return f
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/igungor/tl"
+)
+
+var cmdJSON = &command{
+	UsageLine: "json [-indent] [-filter patterns] file.tl",
+	Short:     "export a TL schema as JSON",
+}
+
+var (
+	jsonIndent = cmdJSON.Flag.Bool("indent", false, "indent the output")
+	jsonFilter = cmdJSON.Flag.String("filter", "", "comma-separated `patterns` of the types and functions to export, e.g. messages.*")
+)
+
+func init() {
+	cmdJSON.Run = runJSON
+}
+
+func runJSON(cmd *command, args []string) {
+	if len(args) != 1 {
+		cmd.Usage()
+	}
+
+	schema, err := loadSchema(args[0])
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if *jsonFilter != "" {
+		schema, err = schema.Filter(strings.Split(*jsonFilter, ",")...)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetEscapeHTML(false)
+	if *jsonIndent {
+		enc.SetIndent("", "  ")
+	}
+	if err := enc.Encode(schema); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// loadSchema parses the given file and builds its schema.
+func loadSchema(filename string) (*tl.Schema, error) {
+	program, err := parseFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	return tl.NewSchema(program)
+}
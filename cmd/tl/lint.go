@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/igungor/tl"
+	"github.com/igungor/tl/lint"
+)
+
+var cmdLint = &command{
+	UsageLine: "lint [-config file] [-rules] file.tl ...",
+	Short:     "report style and safety problems of a TL schema",
+}
+
+var (
+	lintConfig = cmdLint.Flag.String("config", "", "JSON `file` enabling and disabling rules")
+	lintRules  = cmdLint.Flag.Bool("rules", false, "list the available rules and exit")
+)
+
+func init() {
+	cmdLint.Run = runLint
+}
+
+// runLint lints the given files as the files of a single schema, in order.
+func runLint(cmd *command, args []string) {
+	if *lintRules {
+		for _, r := range lint.Rules {
+			fmt.Printf("%-12s %s\n", r.Name, r.Doc)
+		}
+		return
+	}
+
+	if len(args) == 0 {
+		cmd.Usage()
+	}
+
+	var cfg *lint.Config
+	if *lintConfig != "" {
+		var err error
+		cfg, err = lint.LoadConfig(*lintConfig)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	var programs []*tl.Program
+	for _, filename := range args {
+		program, err := parseFile(filename)
+		if err != nil {
+			log.Fatal(err)
+		}
+		programs = append(programs, program)
+	}
+
+	findings, err := lint.Lint(cfg, programs...)
+	if err != nil {
+		log.Fatal(err)
+	}
+	for _, f := range findings {
+		fmt.Fprintln(os.Stdout, f)
+	}
+	if len(findings) > 0 {
+		setExitStatus(1)
+	}
+}
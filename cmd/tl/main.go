@@ -0,0 +1,122 @@
+// Command tl inspects and checks TL (Type Language) schemas.
+//
+// Usage:
+//
+//	tl <command> [arguments]
+//
+// Run 'tl help' for the list of commands.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/igungor/tl"
+)
+
+// A command is a tl subcommand such as tl check.
+type command struct {
+	// Run runs the command with the arguments after the command name.
+	Run func(cmd *command, args []string)
+
+	// UsageLine is the one-line usage message. The first word in the line is
+	// taken to be the command name.
+	UsageLine string
+
+	// Short is the short description shown in the 'tl help' output.
+	Short string
+
+	// Flag is a set of flags specific to this command.
+	Flag flag.FlagSet
+}
+
+// Name returns the command's name: the first word in the usage line.
+func (c *command) Name() string {
+	name := c.UsageLine
+	if i := strings.Index(name, " "); i >= 0 {
+		name = name[:i]
+	}
+	return name
+}
+
+func (c *command) Usage() {
+	fmt.Fprintf(os.Stderr, "usage: tl %s\n", c.UsageLine)
+	c.Flag.PrintDefaults()
+	os.Exit(2)
+}
+
+// commands lists the available commands.
+var commands = []*command{
+	cmdCheck,
+	cmdCompat,
+	cmdDecode,
+	cmdDiff,
+	cmdEncode,
+	cmdJSON,
+	cmdLint,
+	cmdNamespaces,
+	cmdReach,
+	cmdTypeID,
+}
+
+var exitStatus = 0
+
+func setExitStatus(n int) {
+	if exitStatus < n {
+		exitStatus = n
+	}
+}
+
+func main() {
+	flag.Usage = usage
+	flag.Parse()
+	log.SetFlags(0)
+	log.SetPrefix("tl: ")
+
+	args := flag.Args()
+	if len(args) < 1 || args[0] == "help" {
+		usage()
+	}
+
+	for _, cmd := range commands {
+		if cmd.Name() != args[0] {
+			continue
+		}
+
+		cmd.Flag.Usage = cmd.Usage
+		cmd.Flag.Parse(args[1:])
+		cmd.Run(cmd, cmd.Flag.Args())
+		os.Exit(exitStatus)
+	}
+
+	fmt.Fprintf(os.Stderr, "tl: unknown command %q\nRun 'tl help' for usage.\n", args[0])
+	os.Exit(2)
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "usage: tl <command> [arguments]\n\nThe commands are:\n\n")
+	for _, cmd := range commands {
+		fmt.Fprintf(os.Stderr, "\t%-10s %s\n", cmd.Name(), cmd.Short)
+	}
+	os.Exit(2)
+}
+
+// parseFile parses the TL program in the given file.
+func parseFile(filename string) (*tl.Program, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	parser := tl.NewFileParser(filename, f)
+	program := parser.Parse()
+	if err := parser.Err(); err != nil {
+		return nil, err
+	}
+
+	return program, nil
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"fmt"
+	"log"
+)
+
+var cmdNamespaces = &command{
+	UsageLine: "namespaces file.tl",
+	Short:     "list the namespaces of a TL schema",
+}
+
+func init() {
+	cmdNamespaces.Run = runNamespaces
+}
+
+// runNamespaces prints the namespaces along with the number of types,
+// constructors and functions declared in them.
+func runNamespaces(cmd *command, args []string) {
+	if len(args) != 1 {
+		cmd.Usage()
+	}
+
+	schema, err := loadSchema(args[0])
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	for _, ns := range schema.Namespaces() {
+		name := ns.Name
+		if name == "" {
+			name = "(global)"
+		}
+		fmt.Printf("%-16s %4d types %4d constructors %4d functions\n",
+			name, len(ns.Types), len(ns.Constructors), len(ns.Functions))
+	}
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"fmt"
+	"log"
+)
+
+var cmdReach = &command{
+	UsageLine: "reach [-unused] [-why type] file.tl",
+	Short:     "report the types reachable from the functions of a TL schema",
+}
+
+var (
+	reachUnused = cmdReach.Flag.Bool("unused", false, "list the types which are not reachable instead")
+	reachWhy    = cmdReach.Flag.String("why", "", "print the path from a function to the given `type`")
+)
+
+func init() {
+	cmdReach.Run = runReach
+}
+
+func runReach(cmd *command, args []string) {
+	if len(args) != 1 {
+		cmd.Usage()
+	}
+
+	schema, err := loadSchema(args[0])
+	if err != nil {
+		log.Fatal(err)
+	}
+	r := schema.Reachable()
+
+	if *reachWhy != "" {
+		t := schema.Type(*reachWhy)
+		if t == nil {
+			log.Fatalf("unknown type %s", *reachWhy)
+		}
+		path := r.Path(t)
+		if path == nil {
+			fmt.Printf("%s is not reachable\n", t.Name())
+			setExitStatus(1)
+			return
+		}
+		for _, step := range path {
+			fmt.Println(step)
+		}
+		return
+	}
+
+	types := r.Types()
+	if *reachUnused {
+		types = r.Unused()
+	}
+	for _, t := range types {
+		fmt.Printf("%v: %s\n", t.Pos(), t.Name())
+	}
+}
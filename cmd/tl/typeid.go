@@ -0,0 +1,45 @@
+package main
+
+import (
+	"fmt"
+	"log"
+)
+
+var cmdTypeID = &command{
+	UsageLine: "typeid file.tl [type ...]",
+	Short:     "print type numbers",
+}
+
+func init() {
+	cmdTypeID.Run = runTypeID
+}
+
+// runTypeID prints the type numbers of the given types, or all types if none
+// is given.
+func runTypeID(cmd *command, args []string) {
+	if len(args) < 1 {
+		cmd.Usage()
+	}
+
+	schema, err := loadSchema(args[0])
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	names := args[1:]
+	if len(names) == 0 {
+		for _, t := range schema.Types {
+			names = append(names, t.Name())
+		}
+	}
+
+	for _, name := range names {
+		id, ok := schema.TypeID(name)
+		if !ok {
+			log.Printf("unknown type %s", name)
+			setExitStatus(1)
+			continue
+		}
+		fmt.Printf("%08x %s\n", id, name)
+	}
+}
@@ -0,0 +1,284 @@
+// Package compat classifies the changes between two TL schemas by their
+// effect on the wire: whether old clients can still talk to new servers, and
+// new clients to old servers.
+//
+// Values of a type are received by clients if the type is reachable from the
+// result type of a function, and by servers if it is reachable from the
+// fields of a function. A change of a constructor breaks the peers receiving
+// values of its type. Changes of names alone are compatible, as only
+// combinator-names go over the wire.
+package compat
+
+import (
+	"fmt"
+
+	"github.com/igungor/tl"
+	"github.com/igungor/tl/diff"
+)
+
+// Result is the classification of a single change.
+type Result struct {
+	Change     *diff.Change `json:"change"`
+	OldClients bool         `json:"breaks_old_clients"` // old clients can't talk to new servers
+	OldServers bool         `json:"breaks_old_servers"` // new clients can't talk to old servers
+	Reason     string       `json:"reason,omitempty"`   // why the change is breaking
+}
+
+// Breaking reports whether the change breaks old clients or old servers.
+func (r *Result) Breaking() bool { return r.OldClients || r.OldServers }
+
+func (r *Result) String() string {
+	switch {
+	case r.OldClients && r.OldServers:
+		return fmt.Sprintf("breaking (old clients, old servers): %v: %s", r.Change, r.Reason)
+	case r.OldClients:
+		return fmt.Sprintf("breaking (old clients): %v: %s", r.Change, r.Reason)
+	case r.OldServers:
+		return fmt.Sprintf("breaking (old servers): %v: %s", r.Change, r.Reason)
+	}
+	return fmt.Sprintf("compatible: %v", r.Change)
+}
+
+// Breaking reports whether any of the results is breaking.
+func Breaking(results []*Result) bool {
+	for _, r := range results {
+		if r.Breaking() {
+			return true
+		}
+	}
+	return false
+}
+
+// Check classifies the changes from the old schema to the new one, in the
+// order reported by diff.Schemas.
+func Check(old, new *tl.Schema) []*Result {
+	c := &checker{old: old, new: new}
+	c.oldFlow = flowOf(old)
+	c.newFlow = flowOf(new)
+
+	changes := diff.Schemas(old, new)
+
+	// ids of removed combinators, to detect their reuse by added ones
+	c.removed = make(map[string]*diff.Change)
+	for _, ch := range changes {
+		if ch.Kind == diff.Removed {
+			c.removed[idKey(ch.Function, ch.Old)] = ch
+		}
+	}
+
+	results := make([]*Result, len(changes))
+	for i, ch := range changes {
+		r := &Result{Change: ch}
+		if ch.Function {
+			c.function(r)
+		} else {
+			c.constructor(r)
+		}
+		results[i] = r
+	}
+	return results
+}
+
+type checker struct {
+	old, new         *tl.Schema
+	oldFlow, newFlow flow
+	removed          map[string]*diff.Change // removed combinators by idKey
+}
+
+func idKey(function bool, id string) string {
+	if function {
+		return "function " + id
+	}
+	return "constructor " + id
+}
+
+// function classifies a change of a function. Fields are sent by clients and
+// the result by servers.
+func (c *checker) function(r *Result) {
+	ch := r.Change
+	switch ch.Kind {
+	case diff.Added:
+		if o, ok := c.removed[idKey(true, ch.New)]; ok {
+			r.OldClients, r.OldServers = true, true
+			r.Reason = fmt.Sprintf("reuses the id of removed function %s", o.Name)
+		}
+	case diff.Removed:
+		r.OldClients = true
+		r.Reason = "new servers reject calls of old clients"
+	case diff.Renamed:
+	case diff.IDChanged:
+		r.OldClients, r.OldServers = true, true
+		r.Reason = "peers don't recognize the calls of each other"
+	case diff.FieldAdded:
+		if c.newFlag(ch) {
+			// old clients never set the bit
+			r.OldServers = true
+			r.Reason = "old servers don't know the new conditional argument"
+			return
+		}
+		r.OldClients, r.OldServers = true, true
+		r.Reason = "peers serialize the arguments differently"
+	case diff.FieldRemoved, diff.FieldRetyped, diff.FlagChanged, diff.FieldMoved:
+		r.OldClients, r.OldServers = true, true
+		r.Reason = "peers serialize the arguments differently"
+	case diff.ResultChanged:
+		r.OldClients, r.OldServers = true, true
+		r.Reason = "peers serialize the result differently"
+	}
+}
+
+// newFlag reports whether the field added to a function by ch is conditional
+// on a bit of a # field of the old function which no field used.
+func (c *checker) newFlag(ch *diff.Change) bool {
+	oldName := ch.Name
+	if ch.OldName != "" {
+		oldName = ch.OldName
+	}
+	old, new := c.old.Function(oldName), c.new.Function(ch.Name)
+	if old == nil || new == nil {
+		return false
+	}
+
+	var cond *tl.Condition
+	for i, f := range new.Fields {
+		if diff.FieldKey(new.Fields, i) == ch.Field {
+			cond = f.Cond
+		}
+	}
+	if cond == nil || cond.Field == nil || cond.Bit < 0 {
+		return false
+	}
+
+	nat := false
+	for _, f := range old.Fields {
+		if f.Name == cond.Field.Name && f.Cond == nil {
+			nat = true
+		}
+		if f.Cond != nil && f.Cond.Field != nil && f.Cond.Field.Name == cond.Field.Name && f.Cond.Bit == cond.Bit {
+			return false // bit in use
+		}
+	}
+	return nat
+}
+
+// constructor classifies a change of a constructor by the peers receiving
+// values of its type.
+func (c *checker) constructor(r *Result) {
+	ch := r.Change
+
+	// clients and servers receiving values of the type in either schema
+	var clients, servers bool
+	if con := c.old.Constructor(ch.OldName); ch.Kind == diff.Renamed && con != nil {
+		clients, servers = c.oldFlow.receivers(con.Type)
+	} else if con := c.old.Constructor(ch.Name); con != nil {
+		clients, servers = c.oldFlow.receivers(con.Type)
+	}
+	if con := c.new.Constructor(ch.Name); con != nil {
+		cl, sv := c.newFlow.receivers(con.Type)
+		clients, servers = clients || cl, servers || sv
+	}
+
+	switch ch.Kind {
+	case diff.Added:
+		// whether or not peers receive the type now, as reachability may
+		// change in a later layer
+		if o, ok := c.removed[idKey(false, ch.New)]; ok {
+			r.OldClients, r.OldServers = true, true
+			r.Reason = fmt.Sprintf("reuses the id of removed constructor %s", o.Name)
+			return
+		}
+		con := c.new.Constructor(ch.Name)
+		if con == nil || c.old.Type(con.Type.Name()) == nil {
+			return // new types are only sent to peers knowing them
+		}
+		// new servers may send the constructor to old clients and new
+		// clients to old servers
+		r.OldClients, r.OldServers = clients, servers
+		r.Reason = fmt.Sprintf("old peers can't decode the new constructor of %s", con.Type.Name())
+	case diff.Removed:
+		// old servers may send the constructor to new clients and old
+		// clients to new servers
+		r.OldServers, r.OldClients = clients, servers
+		r.Reason = "new peers can't decode the removed constructor"
+	case diff.Renamed:
+	case diff.IDChanged:
+		r.OldClients, r.OldServers = clients || servers, clients || servers
+		r.Reason = "peers don't recognize the id of each other"
+	case diff.FieldAdded, diff.FieldRemoved, diff.FieldRetyped, diff.FlagChanged, diff.FieldMoved:
+		r.OldClients, r.OldServers = clients || servers, clients || servers
+		r.Reason = "peers serialize the constructor differently"
+	case diff.ResultChanged:
+		r.OldClients, r.OldServers = clients || servers, clients || servers
+		r.Reason = "the constructor moved to another type"
+	}
+}
+
+// flow records the types whose values are received by clients and servers.
+// If the schema has no functions, all types are assumed to be received by
+// both.
+type flow struct {
+	all     bool
+	clients map[*tl.Type]bool
+	servers map[*tl.Type]bool
+}
+
+func (f flow) receivers(t *tl.Type) (clients, servers bool) {
+	if f.all {
+		return true, true
+	}
+	return f.clients[t], f.servers[t]
+}
+
+func flowOf(s *tl.Schema) flow {
+	f := flow{all: len(s.Functions) == 0}
+	var args, results []*tl.TypeRef
+	for _, fn := range s.Functions {
+		for _, field := range fn.Fields {
+			args = append(args, field.Type)
+		}
+		results = append(results, fn.Result)
+	}
+	f.servers = reachable(args)
+	f.clients = reachable(results)
+	return f
+}
+
+// reachable returns the types reachable from refs through the fields of their
+// constructors.
+func reachable(refs []*tl.TypeRef) map[*tl.Type]bool {
+	reached := make(map[*tl.Type]bool)
+	var visit func(ref *tl.TypeRef)
+	visit = func(ref *tl.TypeRef) {
+		if ref == nil {
+			return
+		}
+
+		var t *tl.Type
+		switch obj := ref.Object.(type) {
+		case *tl.Type:
+			t = obj
+		case *tl.Constructor:
+			t = obj.Type
+		}
+		if t != nil && !reached[t] {
+			reached[t] = true
+			for _, c := range t.Constructors {
+				for _, field := range c.Fields {
+					visit(field.Type)
+				}
+			}
+		}
+
+		for _, arg := range ref.Args {
+			visit(arg)
+		}
+		for _, field := range ref.Fields {
+			visit(field.Type)
+		}
+	}
+
+	for _, ref := range refs {
+		visit(ref)
+	}
+	return reached
+}
@@ -0,0 +1,204 @@
+package compat
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/igungor/tl"
+	"github.com/igungor/tl/diff"
+)
+
+func schema(t *testing.T, src string) *tl.Schema {
+	parser := tl.NewParser(bytes.NewBufferString(src))
+	program := parser.Parse()
+	if err := parser.Err(); err != nil {
+		t.Fatal(err)
+	}
+	s, err := tl.NewSchema(program)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+const oldSchema = `
+boolFalse#bc799737 = Bool;
+boolTrue#997275b5 = Bool;
+user#d23c81a3 id:int first_name:string = User;
+userEmpty#200250ba id:int = User;
+inputUser#d8292816 id:int = InputUser;
+inputUserSelf#f7c1b13f = InputUser;
+chatOld#6e9c9bc7 id:int title:string = Chat;
+geoPoint#2049d70c long:double lat:double = GeoPoint;
+---functions---
+users.getUser#1 id:InputUser = User;
+chats.getChat#2 id:int = Chat;
+help.getSupport#9cdf08cd = Bool;
+auth.logOut#5717da40 = Bool;
+`
+
+const newSchema = `
+boolFalse#bc799737 = Bool;
+boolTrue#997275b5 = Bool;
+user#d23c81a3 id:int first_name:string = User;
+userEmpty#200250ba id:int = User;
+userDeleted#3 id:int = User;
+inputUser#d8292816 id:int = InputUser;
+chat#6e9c9bc7 id:int title:string = Chat;
+photo#2049d70c id:long = Photo;
+---functions---
+users.getUser#1 id:InputUser = User;
+chats.getChat#2 id:long = Chat;
+auth.logOut#5717da40 = Bool;
+help.getConfig#4 = User;
+`
+
+func TestCheck(t *testing.T) {
+	results := Check(schema(t, oldSchema), schema(t, newSchema))
+
+	want := []struct {
+		change           string
+		clients, servers bool
+	}{
+		{"+ constructor userDeleted #00000003", true, false},
+		{"~ constructor chat: renamed from chatOld", false, false},
+		{"+ constructor photo #2049d70c", true, true},
+		{"- constructor inputUserSelf #f7c1b13f", true, false},
+		{"- constructor geoPoint #2049d70c", false, false},
+		{"~ function chats.getChat: field id retyped: int => long", true, true},
+		{"+ function help.getConfig #00000004", false, false},
+		{"- function help.getSupport #9cdf08cd", true, false},
+	}
+
+	if len(results) != len(want) {
+		for _, r := range results {
+			t.Log(r)
+		}
+		t.Fatalf("got %d results, expected %d", len(results), len(want))
+	}
+	for i, r := range results {
+		w := want[i]
+		if r.Change.String() != w.change {
+			t.Errorf("<%d> got change %q, expected %q", i, r.Change, w.change)
+			continue
+		}
+		if r.OldClients != w.clients || r.OldServers != w.servers {
+			t.Errorf("<%d> %s: got old clients %t, old servers %t, expected %t, %t",
+				i, w.change, r.OldClients, r.OldServers, w.clients, w.servers)
+		}
+	}
+
+	if !Breaking(results) {
+		t.Error("expected breaking changes")
+	}
+}
+
+func TestIDReuse(t *testing.T) {
+	old := schema(t, `
+a#1 x:int = A;
+b#2 = A;
+---functions---
+getA#10 = A;
+`)
+	new := schema(t, `
+a#1 x:int = A;
+c#2 y:long = A;
+---functions---
+getA#10 = A;
+`)
+
+	results := Check(old, new)
+	if len(results) != 2 {
+		t.Fatalf("got %d results, expected 2: %v", len(results), results)
+	}
+
+	r := results[0]
+	if !r.OldClients || !r.OldServers {
+		t.Errorf("expected reused id to break both peers: %v", r)
+	}
+	if want := "reuses the id of removed constructor b"; r.Reason != want {
+		t.Errorf("got reason %q, expected %q", r.Reason, want)
+	}
+}
+
+func TestCompatible(t *testing.T) {
+	old := schema(t, `
+a#1 x:int = A;
+---functions---
+getA#10 = A;
+`)
+	new := schema(t, `
+a#1 x:int = A;
+b#2 = B;
+---functions---
+getA#10 = A;
+getB#11 = B;
+`)
+
+	results := Check(old, new)
+	if len(results) != 2 {
+		t.Fatalf("got %d results, expected 2: %v", len(results), results)
+	}
+	if Breaking(results) {
+		t.Errorf("expected compatible changes, got %v", results)
+	}
+}
+
+func TestFieldMoved(t *testing.T) {
+	old := schema(t, `
+user#d23c81a3 id:int first_name:string last_name:string = User;
+---functions---
+getUser#10 id:int = User;
+`)
+	new := schema(t, `
+user#d23c81a3 first_name:string id:int last_name:string = User;
+---functions---
+getUser#10 id:int = User;
+`)
+
+	results := Check(old, new)
+	if len(results) != 2 {
+		t.Fatalf("got %d results, expected 2: %v", len(results), results)
+	}
+	for _, r := range results {
+		if r.Change.Kind != diff.FieldMoved || !r.OldClients || !r.OldServers {
+			t.Errorf("expected a moved field breaking both peers, got %v", r)
+		}
+	}
+}
+
+func TestFunctionFieldAdded(t *testing.T) {
+	const types = `
+boolFalse#bc799737 = Bool;
+boolTrue#997275b5 = Bool;
+true#3fedd339 = True;
+`
+	const old = types + `
+---functions---
+messages.send#10 flags:# peer:int silent:flags.0?true = Bool;
+`
+
+	var tests = []struct {
+		new              string
+		clients, servers bool
+	}{
+		// new bit: old clients never set it
+		{"messages.send#10 flags:# peer:int silent:flags.0?true reply_to:flags.1?int = Bool;", false, true},
+		// bit in use
+		{"messages.send#10 flags:# peer:int silent:flags.0?true reply_to:flags.0?int = Bool;", true, true},
+		// unconditional
+		{"messages.send#10 flags:# peer:int silent:flags.0?true reply_to:int = Bool;", true, true},
+	}
+
+	for i, tt := range tests {
+		results := Check(schema(t, old), schema(t, types+"---functions---\n"+tt.new))
+		if len(results) != 1 {
+			t.Errorf("<%d> got results %v, expected 1", i, results)
+			continue
+		}
+		r := results[0]
+		if r.Change.Kind != diff.FieldAdded || r.OldClients != tt.clients || r.OldServers != tt.servers {
+			t.Errorf("<%d> got %v, expected old clients %t, old servers %t", i, r, tt.clients, tt.servers)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package tl
+
+import "fmt"
+
+// checkConflicts reports declarations of s which conflict with each other:
+//
+//   - functions named after constructors,
+//   - combinators sharing the same combinator-name, either explicit or
+//     computed,
+//   - fields of a combinator sharing the same name,
+//   - types with both builtin and constructed constructors.
+//
+// Redeclared constructors and functions are reported by NewSchema.
+func (s *Schema) checkConflicts(errs *ErrorList) {
+	for _, f := range s.Functions {
+		if c, ok := s.constructors[f.name]; ok {
+			errs.Add(f.Decl.Pos(), fmt.Sprintf("function %s conflicts with constructor declared at %v", f.name, c.Pos()))
+		}
+	}
+
+	type combinator struct {
+		name string
+		pos  Pos
+	}
+	ids := make(map[uint32]combinator)
+	declareID := func(id uint32, name string, pos Pos) {
+		if prev, ok := ids[id]; ok {
+			errs.Add(pos, fmt.Sprintf("id #%08x of %s collides with %s declared at %v", id, name, prev.name, prev.pos))
+			return
+		}
+		ids[id] = combinator{name, pos}
+	}
+
+	for _, c := range s.Constructors {
+		declareID(c.ID, c.name, c.Pos())
+		if d, ok := c.Decl.(*CombDecl); ok {
+			checkFields(d, errs)
+		}
+	}
+	for _, f := range s.Functions {
+		declareID(f.ID, f.name, f.Decl.Pos())
+		checkFields(f.Decl, errs)
+	}
+
+	for _, t := range s.Types {
+		var builtin, constructed *Constructor
+		for _, c := range t.Constructors {
+			if _, ok := c.Decl.(*BuiltinCombDecl); ok {
+				if builtin == nil {
+					builtin = c
+				}
+			} else if constructed == nil {
+				constructed = c
+			}
+		}
+
+		switch {
+		case constructed == nil:
+		case builtin != nil:
+			errs.Add(constructed.Pos(), fmt.Sprintf("constructor %s of builtin type %s declared at %v",
+				constructed.name, t.name, builtin.Pos()))
+		case s.universe[t.name] != nil:
+			errs.Add(constructed.Pos(), fmt.Sprintf("constructor %s of predeclared type %s", constructed.name, t.name))
+		}
+	}
+}
+
+// checkFields reports fields of d sharing the same name. Fields of repeated
+// arguments are checked separately, as they are scoped to the repetition.
+func checkFields(d *CombDecl, errs *ErrorList) {
+	fields := make(map[string]*Ident)
+	for _, arg := range d.OptArgs {
+		declareFields(d, fields, arg.Names, errs)
+	}
+	for _, arg := range d.Args {
+		declareFields(d, fields, arg.Names, errs)
+	}
+
+	Inspect(d, func(n Node) bool {
+		if rep, ok := n.(*Repetition); ok {
+			fields := make(map[string]*Ident)
+			for _, arg := range rep.Args {
+				declareFields(d, fields, arg.Names, errs)
+			}
+		}
+		return true
+	})
+}
+
+func declareFields(d *CombDecl, fields map[string]*Ident, names []*Ident, errs *ErrorList) {
+	for _, name := range names {
+		if name.Name.Token == ItemUnderscore {
+			continue
+		}
+		if prev, ok := fields[name.Text()]; ok {
+			errs.Add(name.Pos(), fmt.Sprintf("duplicate field %s in %s, previous declaration at %v",
+				name.Text(), d.Name(), prev.Pos()))
+			continue
+		}
+		fields[name.Text()] = name
+	}
+}
@@ -0,0 +1,77 @@
+package tl
+
+import "testing"
+
+func TestChecker_conflicts(t *testing.T) {
+	tests := []struct {
+		src  string
+		errs []string
+	}{
+		{"int ? = Int;\nuser id:int = User;", nil},
+		{
+			"user id:int = User;\nuser id:long = User;",
+			[]string{"2:1: constructor user redeclared, previous declaration at 1:1"},
+		},
+		{
+			"user = User;\n---functions---\nping = User;\nping = User;",
+			[]string{"4:1: function ping redeclared, previous declaration at 3:1"},
+		},
+		{
+			"user = User;\n---functions---\nuser = User;",
+			[]string{
+				"3:1: function user conflicts with constructor declared at 1:1",
+				"3:1: id #bb708740 of user collides with user declared at 1:1",
+			},
+		},
+		{
+			"user#1ec2365e id:int = User;\ngroup#1ec2365e id:int = Group;",
+			[]string{
+				"2:1: explicit id #1ec2365e of group does not match computed id #1d8886c9 of \"group id:int = Group\"",
+				"2:1: id #1ec2365e of group collides with user declared at 1:1",
+			},
+		},
+		{
+			// computed ids collide with explicit ones
+			"user#1d8886c9 id:int = User;\ngroup id:int = Group;",
+			[]string{
+				"1:1: explicit id #1d8886c9 of user does not match computed id #1ec2365e of \"user id:int = User\"",
+				"2:1: id #1d8886c9 of group collides with user declared at 1:1",
+			},
+		},
+		{
+			"user id:int name:string id:long = User;",
+			[]string{"1:25: duplicate field id in user, previous declaration at 1:6"},
+		},
+		{
+			"users {t:Type} t:# [ t:int ] = Users;",
+			[]string{"1:16: duplicate field t in users, previous declaration at 1:8"},
+		},
+		{
+			"int ? = Int;\nintWrapper value:long = Int;",
+			[]string{"2:1: constructor intWrapper of builtin type Int declared at 1:1"},
+		},
+		{
+			"intWrapper value:long = Int;",
+			[]string{"1:1: constructor intWrapper of predeclared type Int"},
+		},
+	}
+
+	var checker Checker
+	for i, tt := range tests {
+		errs := checker.Check(parseString(t, tt.src))
+
+		var got []string
+		for _, err := range errs {
+			got = append(got, err.Error())
+		}
+		if len(got) != len(tt.errs) {
+			t.Errorf("<%d> %q: got errors %q, expected %q", i, tt.src, got, tt.errs)
+			continue
+		}
+		for j := range got {
+			if got[j] != tt.errs[j] {
+				t.Errorf("<%d> %q: got error %q, expected %q", i, tt.src, got[j], tt.errs[j])
+			}
+		}
+	}
+}
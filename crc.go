@@ -0,0 +1,202 @@
+package tl
+
+import (
+	"fmt"
+	"hash/crc32"
+	"strings"
+)
+
+// Description returns the combinator description of d in the canonical form
+// its combinator-name is computed from:
+//
+//   - the combinator-name (#id) is dropped,
+//   - braces of optional arguments and all parentheses are removed,
+//   - angle brackets are replaced with spaces, e.g. Vector<int> => Vector int,
+//   - fields of the form name:flags.N?true are dropped,
+//   - bytes is replaced with string, as they share the same representation,
+//   - there is exactly one space between contiguous lexemes.
+//
+// e.g. getUsers#2d84d5f5 (Vector int) = Vector<User> => getUsers Vector int = Vector User
+func (d *CombDecl) Description() string {
+	words := []string{d.Name()}
+
+	for _, arg := range d.OptArgs {
+		var b strings.Builder
+		b.WriteString(identList(arg.Names))
+		b.WriteString(":")
+		if arg.Excl.IsValid() {
+			b.WriteString("!")
+		}
+		b.WriteString(description(arg.Type))
+		words = append(words, b.String())
+	}
+
+	for _, arg := range d.Args {
+		if isTrueFlag(arg) {
+			continue
+		}
+		words = append(words, argDescription(arg))
+	}
+
+	words = append(words, "=", d.Result.Name.Name)
+	for _, x := range d.Result.Args {
+		words = append(words, description(x))
+	}
+
+	return strings.Join(words, " ")
+}
+
+// Description returns the combinator description of d, e.g. int ? = Int.
+func (d *BuiltinCombDecl) Description() string {
+	return d.Name() + " ? = " + d.Result.Name
+}
+
+// ComputedID returns the combinator-name computed from the description of the
+// combinator. It does not take the explicit combinator-name into account.
+func (d *CombDecl) ComputedID() uint32 { return computeCRC32(d.Description()) }
+
+// ComputedID returns the combinator-name computed from the description of the
+// combinator. It does not take the explicit combinator-name into account.
+func (d *BuiltinCombDecl) ComputedID() uint32 { return computeCRC32(d.Description()) }
+
+// argDescription returns the canonical form of a required argument.
+func argDescription(arg *Arg) string {
+	var b strings.Builder
+	if len(arg.Names) > 0 {
+		b.WriteString(identList(arg.Names))
+		b.WriteString(":")
+	}
+	if c := arg.Cond; c != nil {
+		b.WriteString(c.Field.Text())
+		if c.Bit != nil {
+			b.WriteString(".")
+			b.WriteString(c.Bit.Value)
+		}
+		b.WriteString("?")
+	}
+	if arg.Excl.IsValid() {
+		b.WriteString("!")
+	}
+	b.WriteString(description(arg.Type))
+	return b.String()
+}
+
+// description returns the canonical form of a type expression.
+func description(x Expr) string {
+	switch x := x.(type) {
+	case *TypeIdent:
+		if x.Name == "bytes" {
+			return "string"
+		}
+		return x.Name
+	case *Var:
+		return x.Name
+	case *NatConst:
+		return x.Value
+	case *BareType:
+		return "%" + description(x.X)
+	case *ParenExpr:
+		return description(x.X)
+	case *AppExpr:
+		words := []string{description(x.Fun)}
+		for _, arg := range x.Args {
+			words = append(words, description(arg))
+		}
+		return strings.Join(words, " ")
+	case *SumExpr:
+		return description(x.X) + "+" + description(x.Y)
+	case *Repetition:
+		words := []string{"["}
+		if x.Mult != nil {
+			words[0] = description(x.Mult) + "*["
+		}
+		for _, arg := range x.Args {
+			words = append(words, argDescription(arg))
+		}
+		words = append(words, "]")
+		return strings.Join(words, " ")
+	}
+	return ""
+}
+
+// isTrueFlag reports whether arg is a conditional field of type true, e.g.
+// silent:flags.5?true. Such fields are only represented by their flag bit.
+func isTrueFlag(arg *Arg) bool {
+	if arg.Cond == nil || arg.Cond.Bit == nil {
+		return false
+	}
+	typ, ok := arg.Type.(*TypeIdent)
+	return ok && typ.Name == "true"
+}
+
+func identList(list []*Ident) string {
+	names := make([]string, len(list))
+	for i, id := range list {
+		names[i] = id.Text()
+	}
+	return strings.Join(names, " ")
+}
+
+// computeCRC32 calculates the combinator-name for the given combinator-description.
+// e.g. int ? = Int => a8509bda
+func computeCRC32(s string) uint32 {
+	return crc32.ChecksumIEEE([]byte(s))
+}
+
+// An IDMismatch describes a combinator whose explicit combinator-name differs
+// from the one computed from its description.
+type IDMismatch struct {
+	Decl        Combinator
+	Explicit    uint32
+	Computed    uint32
+	Description string // canonical description the computed id is based on
+}
+
+func (m *IDMismatch) Error() string {
+	return fmt.Sprintf("%v: %s", m.Decl.Pos(), m.message())
+}
+
+func (m *IDMismatch) message() string {
+	return fmt.Sprintf("explicit id #%08x of %s does not match computed id #%08x of %q",
+		m.Explicit, m.Decl.Name(), m.Computed, m.Description)
+}
+
+// VerifyIDs recomputes the combinator-name of every combinator with an
+// explicit combinator-name in prog and returns the mismatches in source order.
+func VerifyIDs(prog *Program) []*IDMismatch {
+	var mismatches []*IDMismatch
+
+	for _, decls := range [][]Declaration{prog.Constructors, prog.Functions, prog.Types} {
+		for _, decl := range decls {
+			var (
+				id   *FullCombinatorId
+				desc string
+			)
+
+			switch d := decl.(type) {
+			case *CombDecl:
+				id, desc = d.Id, d.Description()
+			case *BuiltinCombDecl:
+				id, desc = d.Id, d.Description()
+			default:
+				continue
+			}
+
+			explicit, ok := id.ID()
+			if !ok {
+				continue
+			}
+
+			if computed := computeCRC32(desc); computed != explicit {
+				mismatches = append(mismatches, &IDMismatch{
+					Decl:        decl.(Combinator),
+					Explicit:    explicit,
+					Computed:    computed,
+					Description: desc,
+				})
+			}
+		}
+	}
+
+	return mismatches
+}
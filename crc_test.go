@@ -0,0 +1,91 @@
+package tl
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+)
+
+func TestCombDecl_ComputedID(t *testing.T) {
+	var tests = []struct {
+		s    string
+		desc string
+		id   uint32
+	}{
+		{`int ? = Int;`, "int ? = Int", 0xa8509bda},
+		{`user#d23c81a3 id:int first_name:string last_name:string = User;`, "user id:int first_name:string last_name:string = User", 0xd23c81a3},
+		{`getUsers#2d84d5f5 (Vector int) = Vector User;`, "getUsers Vector int = Vector User", 0x2d84d5f5},
+		{`getUsers (Vector int) = Vector<User>;`, "getUsers Vector int = Vector User", 0x2d84d5f5},
+		{`getUser int = User;`, "getUser int = User", 0xb0f732d5},
+		{`vector#1cb5c415 {t:Type} # [ t ] = Vector t;`, "vector t:Type # [ t ] = Vector t", 0x1cb5c415},
+		{`invokeWithLayer#da9b0d0d {X:Type} layer:int query:!X = X;`, "invokeWithLayer X:Type layer:int query:!X = X", 0xda9b0d0d},
+		{`auth.importAuthorization#e3ef9613 id:int bytes:bytes = auth.Authorization;`, "auth.importAuthorization id:int bytes:string = auth.Authorization", 0xe3ef9613},
+		{`msg flags:# silent:flags.5?true id:flags.0?int = Msg;`, "msg flags:# id:flags.0?int = Msg", 0},
+		{`t {n:#} n*[x:int (a b:%(Vector long))] = T (n+1);`, "t n:# n*[ x:int a b:%Vector long ] = T n+1", 0},
+	}
+
+	for i, tt := range tests {
+		parser := NewParser(bytes.NewBufferString(tt.s))
+		program := parser.Parse()
+		if parser.Err() != nil {
+			t.Fatalf("<%d> got error: %v", i, parser.Err())
+		}
+
+		comb := program.Constructors[0].(Combinator)
+		desc := comb.(interface{ Description() string }).Description()
+		if desc != tt.desc {
+			t.Errorf("<%d> bad description for %q: got %q, expected %q", i, tt.s, desc, tt.desc)
+		}
+
+		if tt.id == 0 {
+			tt.id = computeCRC32(tt.desc)
+		}
+		if id := comb.ComputedID(); id != tt.id {
+			t.Errorf("<%d> bad id for %q: got %08x, expected %08x", i, tt.s, id, tt.id)
+		}
+	}
+}
+
+// TestCombDecl_ComputedID_schema checks the computed ids against the explicit
+// ids in schema.tl.
+func TestCombDecl_ComputedID_schema(t *testing.T) {
+	// legacy constructors whose ids are not computed from their descriptions
+	legacy := map[string]bool{
+		"document_l19": true,
+	}
+
+	src, err := ioutil.ReadFile("schema.tl")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parser := NewParser(bytes.NewReader(src))
+	program := parser.Parse()
+	if parser.Err() != nil {
+		t.Fatal(parser.Err())
+	}
+
+	var n int
+	for _, decls := range [][]Declaration{program.Constructors, program.Functions} {
+		for _, decl := range decls {
+			d, ok := decl.(*CombDecl)
+			if !ok || legacy[d.Name()] {
+				continue
+			}
+
+			want, ok := d.Id.ID()
+			if !ok {
+				continue
+			}
+
+			n++
+			if id := d.ComputedID(); id != uint32(want) {
+				t.Errorf("%v: bad id for %v: got %08x, expected %08x (%q)", d.Pos(), d.Name(), id, want, d.Description())
+			}
+		}
+	}
+
+	if n == 0 {
+		t.Error("no combinators with explicit ids")
+	}
+}
@@ -0,0 +1,380 @@
+// Package diff computes the differences between two TL schemas, e.g. two
+// layers of the Telegram API.
+//
+// Constructors and functions are matched by name. Combinators missing from
+// one of the schemas are matched by their field layout, the names, types and
+// conditions of their fields and their result type, and are reported as
+// renames if exactly one combinator of either schema has the layout.
+// Combinators without fields must also have the same combinator-name, as
+// their layout identifies nothing. The remaining ones are reported as added
+// or removed.
+//
+// Fields are matched by name, and anonymous fields by their index among the
+// anonymous fields, e.g. _1 for the int of pair x:long string int = Pair.
+package diff
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/igungor/tl"
+)
+
+// Kind is the kind of a change.
+type Kind int
+
+const (
+	Added         Kind = iota // combinator added
+	Removed                   // combinator removed
+	Renamed                   // combinator renamed, with the same field layout
+	IDChanged                 // combinator-name changed
+	FieldAdded                // field added
+	FieldRemoved              // field removed
+	FieldRetyped              // type of a field changed
+	FlagChanged               // condition of a conditional field changed, e.g. flags.1 => flags.2
+	ResultChanged             // result type changed
+	FieldMoved                // serialized field moved relative to the other fields
+)
+
+var kinds = [...]string{
+	Added:         "added",
+	Removed:       "removed",
+	Renamed:       "renamed",
+	IDChanged:     "id-changed",
+	FieldAdded:    "field-added",
+	FieldRemoved:  "field-removed",
+	FieldRetyped:  "field-retyped",
+	FlagChanged:   "flag-changed",
+	ResultChanged: "result-changed",
+	FieldMoved:    "field-moved",
+}
+
+func (k Kind) String() string {
+	if 0 <= k && int(k) < len(kinds) {
+		return kinds[k]
+	}
+	return "Kind(" + strconv.Itoa(int(k)) + ")"
+}
+
+// MarshalText implements the encoding.TextMarshaler interface.
+func (k Kind) MarshalText() ([]byte, error) { return []byte(k.String()), nil }
+
+// Change describes a single difference between two schemas.
+type Change struct {
+	Kind     Kind   `json:"kind"`
+	Function bool   `json:"function"`           // change of a function rather than a constructor
+	Name     string `json:"name"`               // name of the combinator, the new one for renames
+	OldName  string `json:"old_name,omitempty"` // old name for renames
+	Field    string `json:"field,omitempty"`    // name of the changed field, if any
+	Old      string `json:"old,omitempty"`      // old value, e.g. the old id or field type
+	New      string `json:"new,omitempty"`      // new value
+
+	OldPos tl.Pos `json:"-"`
+	NewPos tl.Pos `json:"-"`
+}
+
+func (c *Change) String() string {
+	what := "constructor"
+	if c.Function {
+		what = "function"
+	}
+
+	switch c.Kind {
+	case Added:
+		return fmt.Sprintf("+ %s %s %s", what, c.Name, c.New)
+	case Removed:
+		return fmt.Sprintf("- %s %s %s", what, c.Name, c.Old)
+	case Renamed:
+		return fmt.Sprintf("~ %s %s: renamed from %s", what, c.Name, c.OldName)
+	case IDChanged:
+		return fmt.Sprintf("~ %s %s: id %s => %s", what, c.Name, c.Old, c.New)
+	case FieldAdded:
+		return fmt.Sprintf("~ %s %s: field %s added: %s", what, c.Name, c.Field, c.New)
+	case FieldRemoved:
+		return fmt.Sprintf("~ %s %s: field %s removed: %s", what, c.Name, c.Field, c.Old)
+	case FieldRetyped:
+		return fmt.Sprintf("~ %s %s: field %s retyped: %s => %s", what, c.Name, c.Field, c.Old, c.New)
+	case FlagChanged:
+		return fmt.Sprintf("~ %s %s: field %s condition: %s => %s", what, c.Name, c.Field, c.Old, c.New)
+	case ResultChanged:
+		return fmt.Sprintf("~ %s %s: result %s => %s", what, c.Name, c.Old, c.New)
+	case FieldMoved:
+		return fmt.Sprintf("~ %s %s: field %s moved: %s => %s", what, c.Name, c.Field, c.Old, c.New)
+	}
+	return fmt.Sprintf("? %s %s", what, c.Name)
+}
+
+// JSON returns the changes as a JSON array.
+func JSON(changes []*Change) ([]byte, error) {
+	if changes == nil {
+		changes = []*Change{}
+	}
+	return json.Marshal(changes)
+}
+
+// combinator is the common view of constructors and functions.
+type combinator struct {
+	name   string
+	id     uint32
+	fields []*tl.Field
+	result string
+	pos    tl.Pos
+}
+
+func constructors(s *tl.Schema) []*combinator {
+	var list []*combinator
+	for _, c := range s.Constructors {
+		list = append(list, &combinator{c.Name(), c.ID, c.Fields, c.Type.Name(), c.Pos()})
+	}
+	return list
+}
+
+func functions(s *tl.Schema) []*combinator {
+	var list []*combinator
+	for _, f := range s.Functions {
+		list = append(list, &combinator{f.Name(), f.ID, f.Fields, f.Result.String(), f.Decl.Pos()})
+	}
+	return list
+}
+
+// Schemas returns the changes from the old schema to the new one. Changes of
+// constructors come first, then the ones of functions, in the order of the
+// new schema; removals come last.
+func Schemas(old, new *tl.Schema) []*Change {
+	changes := combinators(constructors(old), constructors(new), false)
+	return append(changes, combinators(functions(old), functions(new), true)...)
+}
+
+func combinators(old, new []*combinator, function bool) []*Change {
+	var changes []*Change
+
+	oldByName := make(map[string]*combinator)
+	for _, c := range old {
+		oldByName[c.name] = c
+	}
+	newByName := make(map[string]bool)
+	for _, c := range new {
+		newByName[c.name] = true
+	}
+
+	// layouts of the combinators missing from the old schema, to match
+	// renames one to one
+	added := make(map[string]int)
+	for _, c := range new {
+		if _, ok := oldByName[c.name]; !ok {
+			added[layout(c)]++
+		}
+	}
+
+	// combinators missing from the other schema, candidates for renames
+	var removed []*combinator
+	for _, c := range old {
+		if !newByName[c.name] {
+			removed = append(removed, c)
+		}
+	}
+
+	for _, n := range new {
+		o, ok := oldByName[n.name]
+		if !ok {
+			if o = matchLayout(removed, n, added); o == nil {
+				changes = append(changes, &Change{Kind: Added, Function: function, Name: n.name, New: idString(n.id), NewPos: n.pos})
+				continue
+			}
+			changes = append(changes, &Change{Kind: Renamed, Function: function, Name: n.name, OldName: o.name, OldPos: o.pos, NewPos: n.pos})
+		}
+		changes = append(changes, compare(o, n, function)...)
+	}
+
+	for _, o := range removed {
+		if o != nil {
+			changes = append(changes, &Change{Kind: Removed, Function: function, Name: o.name, Old: idString(o.id), OldPos: o.pos})
+		}
+	}
+
+	return changes
+}
+
+// matchLayout returns the only combinator of list with the same layout as c
+// and removes it from the list. It returns nil if there is none, if there are
+// several, or if c is not the only added combinator with the layout, whose
+// counts are in added.
+func matchLayout(list []*combinator, c *combinator, added map[string]int) *combinator {
+	l := layout(c)
+	if added[l] != 1 {
+		return nil
+	}
+
+	match := -1
+	for i, o := range list {
+		if o != nil && layout(o) == l {
+			if match >= 0 {
+				return nil // ambiguous
+			}
+			match = i
+		}
+	}
+	if match < 0 {
+		return nil
+	}
+	o := list[match]
+	list[match] = nil
+	return o
+}
+
+// layout returns the names, types and conditions of the fields of c along
+// with its result type, e.g. "flags:# text:flags.0?string = User", and the
+// combinator-name if c has no fields, e.g. "= InputPeer #7f3b18ea".
+func layout(c *combinator) string {
+	var b strings.Builder
+	for _, f := range c.fields {
+		b.WriteString(f.Name)
+		b.WriteString(":")
+		b.WriteString(fieldType(f))
+		b.WriteString(" ")
+	}
+	b.WriteString("= ")
+	b.WriteString(c.result)
+	if len(c.fields) == 0 {
+		b.WriteString(" ")
+		b.WriteString(idString(c.id))
+	}
+	return b.String()
+}
+
+// compare returns the changes between two versions of the same combinator.
+func compare(o, n *combinator, function bool) []*Change {
+	var changes []*Change
+	change := func(kind Kind, field, old, new string) {
+		changes = append(changes, &Change{
+			Kind:     kind,
+			Function: function,
+			Name:     n.name,
+			Field:    field,
+			Old:      old,
+			New:      new,
+			OldPos:   o.pos,
+			NewPos:   n.pos,
+		})
+	}
+
+	if o.id != n.id {
+		change(IDChanged, "", idString(o.id), idString(n.id))
+	}
+
+	oldFields := make(map[string]*tl.Field)
+	for i, f := range o.fields {
+		oldFields[FieldKey(o.fields, i)] = f
+	}
+	newFields := make(map[string]bool)
+	for i, f := range n.fields {
+		key := FieldKey(n.fields, i)
+		newFields[key] = true
+
+		of, ok := oldFields[key]
+		if !ok {
+			change(FieldAdded, key, "", fieldType(f))
+			continue
+		}
+		if ot, nt := typeString(of), typeString(f); ot != nt {
+			change(FieldRetyped, key, ot, nt)
+		}
+		if oc, nc := condString(of), condString(f); oc != nc {
+			change(FlagChanged, key, oc, nc)
+		}
+	}
+	for i, f := range o.fields {
+		if key := FieldKey(o.fields, i); !newFields[key] {
+			change(FieldRemoved, key, fieldType(f), "")
+		}
+	}
+
+	// the order of the serialized fields of both versions
+	oldOrder, newOrder := order(o.fields, n.fields), order(n.fields, o.fields)
+	for i := range n.fields {
+		key := FieldKey(n.fields, i)
+		if oi, ok := oldOrder[key]; ok && oi != newOrder[key] {
+			change(FieldMoved, key, strconv.Itoa(oi), strconv.Itoa(newOrder[key]))
+		}
+	}
+
+	if o.result != n.result {
+		change(ResultChanged, "", o.result, n.result)
+	}
+
+	return changes
+}
+
+// order returns the indices of the serialized fields of a combinator among
+// the ones present in the other version of it, so that adding or removing a
+// field doesn't move the others.
+func order(fields, other []*tl.Field) map[string]int {
+	keys := make(map[string]bool)
+	for i := range other {
+		keys[FieldKey(other, i)] = true
+	}
+
+	indices := make(map[string]int)
+	for i, f := range fields {
+		if key := FieldKey(fields, i); keys[key] && !f.Optional {
+			indices[key] = len(indices)
+		}
+	}
+	return indices
+}
+
+// FieldKey returns the key of the i-th of fields in changes: its name, or _N
+// for the N-th anonymous field, so that adding a named field doesn't change
+// the keys of the anonymous ones.
+func FieldKey(fields []*tl.Field, i int) string {
+	if name := fields[i].Name; name != "" {
+		return name
+	}
+	n := 0
+	for _, f := range fields[:i] {
+		if f.Name == "" {
+			n++
+		}
+	}
+	return "_" + strconv.Itoa(n)
+}
+
+// fieldType returns the type of the field including its condition, e.g.
+// flags.2?string.
+func fieldType(f *tl.Field) string {
+	if f.Cond == nil {
+		return typeString(f)
+	}
+	return condString(f) + "?" + typeString(f)
+}
+
+func typeString(f *tl.Field) string {
+	if f.Excl {
+		return "!" + f.Type.String()
+	}
+	return f.Type.String()
+}
+
+// condString returns the condition of a conditional field, e.g. flags.2, or
+// "-" for unconditional fields.
+func condString(f *tl.Field) string {
+	c := f.Cond
+	if c == nil {
+		return "-"
+	}
+
+	name := "?"
+	if c.Field != nil {
+		name = c.Field.Name
+	}
+	if c.Bit < 0 {
+		return name
+	}
+	return name + "." + strconv.Itoa(c.Bit)
+}
+
+func idString(id uint32) string {
+	return fmt.Sprintf("#%08x", id)
+}
@@ -0,0 +1,213 @@
+package diff
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/igungor/tl"
+)
+
+func schema(t *testing.T, src string) *tl.Schema {
+	parser := tl.NewParser(bytes.NewBufferString(src))
+	program := parser.Parse()
+	if err := parser.Err(); err != nil {
+		t.Fatal(err)
+	}
+	s, err := tl.NewSchema(program)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+const oldSchema = `
+boolFalse#bc799737 = Bool;
+boolTrue#997275b5 = Bool;
+user#d23c81a3 id:int first_name:string photo:Bool = User;
+userEmpty#200250ba id:int = User;
+chatOld#6e9c9bc7 id:int title:string = Chat;
+geoPoint#2049d70c long:double lat:double = GeoPoint;
+message#1 flags:# id:int text:flags.0?string media:flags.1?Bool = Message;
+---functions---
+auth.logOut#5717da40 = Bool;
+users.getUsers#d91a548 id:int = User;
+help.getSupport#9cdf08cd = Bool;
+`
+
+const newSchema = `
+boolFalse#bc799737 = Bool;
+boolTrue#997275b5 = Bool;
+user#2e13f4c3 id:long first_name:string verified:Bool = User;
+userEmpty#200250ba id:int = User;
+chat#7328bdb id:int title:string = Chat;
+message#1 flags:# id:int text:flags.0?string media:flags.2?Bool = Message;
+document#2 id:long = Document;
+---functions---
+auth.logOut#5717da40 = Bool;
+users.getUsers#d91a548 id:int = Chat;
+`
+
+func TestSchemas(t *testing.T) {
+	changes := Schemas(schema(t, oldSchema), schema(t, newSchema))
+
+	want := []string{
+		"~ constructor user: id #d23c81a3 => #2e13f4c3",
+		"~ constructor user: field id retyped: int => long",
+		"~ constructor user: field verified added: Bool",
+		"~ constructor user: field photo removed: Bool",
+		"~ constructor chat: renamed from chatOld",
+		"~ constructor chat: id #6e9c9bc7 => #07328bdb",
+		"~ constructor message: field media condition: flags.1 => flags.2",
+		"+ constructor document #00000002",
+		"- constructor geoPoint #2049d70c",
+		"~ function users.getUsers: result User => Chat",
+		"- function help.getSupport #9cdf08cd",
+	}
+
+	if len(changes) != len(want) {
+		for _, c := range changes {
+			t.Log(c)
+		}
+		t.Fatalf("got %d changes, expected %d", len(changes), len(want))
+	}
+	for i, c := range changes {
+		if c.String() != want[i] {
+			t.Errorf("<%d> got %q, expected %q", i, c.String(), want[i])
+		}
+	}
+
+	if changes := Schemas(schema(t, oldSchema), schema(t, oldSchema)); len(changes) != 0 {
+		t.Errorf("expected no changes between identical schemas, got %v", changes)
+	}
+}
+
+func TestSchemas_fieldMoved(t *testing.T) {
+	old := schema(t, "user#d23c81a3 id:int first_name:string last_name:string = User;")
+	new := schema(t, "user#d23c81a3 first_name:string id:int last_name:string = User;")
+
+	want := []string{
+		"~ constructor user: field first_name moved: 1 => 0",
+		"~ constructor user: field id moved: 0 => 1",
+	}
+	changes := Schemas(old, new)
+	if len(changes) != len(want) {
+		t.Fatalf("got changes %v, expected %q", changes, want)
+	}
+	for i, c := range changes {
+		if c.String() != want[i] {
+			t.Errorf("<%d> got %q, expected %q", i, c.String(), want[i])
+		}
+	}
+
+	// adding a field doesn't move the following ones
+	new = schema(t, "user#d23c81a3 id:int username:string first_name:string last_name:string = User;")
+	for _, c := range Schemas(old, new) {
+		if c.Kind != FieldAdded {
+			t.Errorf("unexpected change %v", c)
+		}
+	}
+}
+
+func TestSchemas_renames(t *testing.T) {
+	var tests = []struct {
+		old, new string
+		want     []string
+	}{
+		{
+			"inputPeerEmpty#7f3b18ea = InputPeer;",
+			"foo#1 = InputPeer;",
+			[]string{"+ constructor foo #00000001", "- constructor inputPeerEmpty #7f3b18ea"},
+		},
+		{
+			"inputPeerEmpty#7f3b18ea = InputPeer;",
+			"inputPeerNone#7f3b18ea = InputPeer;",
+			[]string{"~ constructor inputPeerNone: renamed from inputPeerEmpty"},
+		},
+		{
+			"peerA#1 id:int = Peer;",
+			"peerB#2 user_id:int = Peer;",
+			[]string{"+ constructor peerB #00000002", "- constructor peerA #00000001"},
+		},
+		{
+			"peerA#1 id:int = Peer; peerB#2 id:int = Peer;",
+			"peerC#3 id:int = Peer;",
+			[]string{"+ constructor peerC #00000003", "- constructor peerA #00000001", "- constructor peerB #00000002"},
+		},
+		{
+			"peerA#1 id:int = Peer;",
+			"peerB#2 id:int = Peer; peerC#3 id:int = Peer;",
+			[]string{"+ constructor peerB #00000002", "+ constructor peerC #00000003", "- constructor peerA #00000001"},
+		},
+	}
+
+	for i, tt := range tests {
+		changes := Schemas(schema(t, tt.old), schema(t, tt.new))
+		var got []string
+		for _, c := range changes {
+			got = append(got, c.String())
+		}
+		if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+			t.Errorf("<%d> got changes %q, expected %q", i, got, tt.want)
+		}
+	}
+}
+
+// TestSchemas_anonymous checks that a field inserted before an anonymous one
+// doesn't change its key.
+func TestSchemas_anonymous(t *testing.T) {
+	old := schema(t, "pair#1 int string = Pair;")
+	new := schema(t, "pair#1 x:long int y:int string = Pair;")
+
+	want := []string{
+		"~ constructor pair: field x added: long",
+		"~ constructor pair: field y added: int",
+	}
+	changes := Schemas(old, new)
+	if len(changes) != len(want) {
+		t.Fatalf("got changes %v, expected %q", changes, want)
+	}
+	for i, c := range changes {
+		if c.String() != want[i] {
+			t.Errorf("<%d> got %q, expected %q", i, c.String(), want[i])
+		}
+	}
+
+	new = schema(t, "pair#1 x:long string int = Pair;")
+	want = []string{
+		"~ constructor pair: field x added: long",
+		"~ constructor pair: field _0 retyped: int => string",
+		"~ constructor pair: field _1 retyped: string => int",
+	}
+	changes = Schemas(old, new)
+	if len(changes) != len(want) {
+		t.Fatalf("got changes %v, expected %q", changes, want)
+	}
+	for i, c := range changes {
+		if c.String() != want[i] {
+			t.Errorf("<%d> got %q, expected %q", i, c.String(), want[i])
+		}
+	}
+}
+
+func TestJSON(t *testing.T) {
+	changes := []*Change{
+		{Kind: Renamed, Name: "chat", OldName: "chatOld"},
+		{Kind: FieldRetyped, Function: true, Name: "users.getUsers", Field: "id", Old: "int", New: "long"},
+	}
+
+	data, err := JSON(changes)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `[{"kind":"renamed","function":false,"name":"chat","old_name":"chatOld"},` +
+		`{"kind":"field-retyped","function":true,"name":"users.getUsers","field":"id","old":"int","new":"long"}]`
+	if string(data) != want {
+		t.Errorf("bad JSON:\ngot  %s\nwant %s", data, want)
+	}
+
+	if data, _ := JSON(nil); string(data) != "[]" {
+		t.Errorf("bad JSON for no changes: %s", data)
+	}
+}
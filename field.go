@@ -0,0 +1,234 @@
+package tl
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Field represents a field of a combinator, e.g. the peer in
+// `messages.getHistory peer:InputPeer offset:int = messages.Messages`.
+type Field struct {
+	Name     string     // field name; empty for anonymous fields, e.g. the # in vector
+	Type     *TypeRef   // resolved type of the field
+	Optional bool       // optional (implicit) field, e.g. {t:Type}; not serialized
+	Excl     bool       // type is preceded by '!', e.g. query:!X
+	Cond     *Condition // condition of a conditional field, or nil
+
+	Arg Node // *Arg or *OptionalArg
+}
+
+// Condition represents the condition of a conditional field, e.g. the
+// flags.3 in x:flags.3?int.
+type Condition struct {
+	Field *Field // field of type # holding the flags
+	Bit   int    // bit of the flags field, -1 if the field is only tested for non-zero
+}
+
+// TypeRef represents a resolved type expression.
+type TypeRef struct {
+	// Object is the referenced object: a *Type, a *Constructor for bare
+	// types named after their constructor, a *Builtin or a *TypeVar. It is
+	// nil for repetitions, nat constants and sums, and unresolved
+	// references.
+	Object Object
+
+	Boxing Boxing     // how the type is serialized, boxed or bare
+	Args   []*TypeRef // arguments of a type application, e.g. int in Vector<int>
+	Mult   *TypeRef   // multiplicity of a repetition, nil if implicit
+	Fields []*Field   // fields of a repetition, e.g. [ t ]
+
+	Expr Expr // type expression the reference is resolved from, or nil for result types
+}
+
+// String returns the type in the angle bracket form, e.g. Vector<int>.
+func (t *TypeRef) String() string {
+	if t.Fields != nil {
+		var b strings.Builder
+		if t.Mult != nil {
+			b.WriteString(t.Mult.String())
+			b.WriteString("*")
+		}
+		b.WriteString("[")
+		for i, f := range t.Fields {
+			if i > 0 {
+				b.WriteString(" ")
+			}
+			if f.Name != "" {
+				b.WriteString(f.Name)
+				b.WriteString(":")
+			}
+			b.WriteString(f.Type.String())
+		}
+		b.WriteString("]")
+		return b.String()
+	}
+
+	if t.Object == nil {
+		if t.Expr == nil {
+			return ""
+		}
+		return exprString(t.Expr)
+	}
+
+	var b strings.Builder
+	if _, ok := t.Object.(*Type); ok && t.Boxing == BarePercent {
+		b.WriteString("%")
+	}
+	b.WriteString(t.Object.Name())
+	if len(t.Args) > 0 {
+		b.WriteString("<")
+		for i, arg := range t.Args {
+			if i > 0 {
+				b.WriteString(",")
+			}
+			b.WriteString(arg.String())
+		}
+		b.WriteString(">")
+	}
+	return b.String()
+}
+
+// buildFields builds the fields of the combinators and the result types of
+// the functions of s.
+func (s *Schema) buildFields() {
+	for _, c := range s.Constructors {
+		if d, ok := c.Decl.(*CombDecl); ok {
+			c.Fields = s.fields(d)
+		}
+	}
+	for _, f := range s.Functions {
+		f.Fields = s.fields(f.Decl)
+		f.Result = s.resultRef(f.Decl.Result)
+	}
+}
+
+// fields returns the fields of d, optional fields first.
+func (s *Schema) fields(d *CombDecl) []*Field {
+	var fields []*Field
+	for _, arg := range d.OptArgs {
+		typ := s.typeRef(arg.Type)
+		for _, name := range arg.Names {
+			fields = append(fields, &Field{
+				Name:     name.Text(),
+				Type:     typ,
+				Optional: true,
+				Excl:     arg.Excl.IsValid(),
+				Arg:      arg,
+			})
+		}
+	}
+	return s.argFields(fields, d.Args)
+}
+
+// argFields appends the fields of the given arguments to fields. Conditions
+// refer to the fields declared earlier in the list.
+func (s *Schema) argFields(fields []*Field, args []*Arg) []*Field {
+	for _, arg := range args {
+		var cond *Condition
+		if arg.Cond != nil {
+			cond = &Condition{Field: lookupField(fields, arg.Cond.Field.Text()), Bit: -1}
+			if arg.Cond.Bit != nil {
+				if bit, err := strconv.Atoi(arg.Cond.Bit.Value); err == nil {
+					cond.Bit = bit
+				}
+			}
+		}
+
+		typ := s.typeRef(arg.Type)
+		if typ.Fields != nil {
+			resolveConds(typ.Fields, fields)
+		}
+		newField := func(name string) *Field {
+			return &Field{Name: name, Type: typ, Excl: arg.Excl.IsValid(), Cond: cond, Arg: arg}
+		}
+
+		if len(arg.Names) == 0 {
+			fields = append(fields, newField(""))
+			continue
+		}
+		for _, name := range arg.Names {
+			if name.Name.Token == ItemUnderscore {
+				fields = append(fields, newField(""))
+				continue
+			}
+			fields = append(fields, newField(name.Text()))
+		}
+	}
+	return fields
+}
+
+// resolveConds resolves the conditions of the fields of a repetition which
+// refer to the fields declared before it in the enclosing combinator, e.g. the
+// flags of flags:# n:# rows:n*[x:flags.0?int].
+func resolveConds(fields, outer []*Field) {
+	for _, f := range fields {
+		if arg, ok := f.Arg.(*Arg); ok && f.Cond != nil && f.Cond.Field == nil {
+			f.Cond.Field = lookupField(outer, arg.Cond.Field.Text())
+		}
+		if f.Type.Fields != nil {
+			resolveConds(f.Type.Fields, outer)
+		}
+	}
+}
+
+// lookupField returns the last field with the given name, or nil.
+func lookupField(fields []*Field, name string) *Field {
+	for i := len(fields) - 1; i >= 0; i-- {
+		if fields[i].Name == name {
+			return fields[i]
+		}
+	}
+	return nil
+}
+
+// typeRef resolves the type expression x.
+func (s *Schema) typeRef(x Expr) *TypeRef {
+	switch x := x.(type) {
+	case *TypeIdent:
+		ref := &TypeRef{Object: s.Uses[x], Expr: x}
+		switch ref.Object.(type) {
+		case *Constructor:
+			ref.Boxing = BareConstructor
+		case *Builtin:
+			ref.Boxing = BareImplicit
+		}
+		return ref
+	case *Var:
+		return &TypeRef{Object: s.Uses[x], Expr: x}
+	case *BareType:
+		ref := s.typeRef(x.X)
+		if ref.Boxing == Boxed {
+			ref.Boxing = BarePercent
+		}
+		ref.Expr = x
+		return ref
+	case *ParenExpr:
+		ref := s.typeRef(x.X)
+		ref.Expr = x
+		return ref
+	case *AppExpr:
+		ref := s.typeRef(x.Fun)
+		for _, arg := range x.Args {
+			ref.Args = append(ref.Args, s.typeRef(arg))
+		}
+		ref.Expr = x
+		return ref
+	case *Repetition:
+		ref := &TypeRef{Boxing: BareImplicit, Expr: x}
+		if x.Mult != nil {
+			ref.Mult = s.typeRef(x.Mult)
+		}
+		ref.Fields = s.argFields(nil, x.Args)
+		return ref
+	}
+	return &TypeRef{Expr: x}
+}
+
+// resultRef resolves the result type of a combinator.
+func (s *Schema) resultRef(r *ResultType) *TypeRef {
+	ref := &TypeRef{Object: s.Uses[r.Name]}
+	for _, arg := range r.Args {
+		ref.Args = append(ref.Args, s.typeRef(arg))
+	}
+	return ref
+}
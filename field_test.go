@@ -0,0 +1,95 @@
+package tl
+
+import "testing"
+
+func TestSchema_fields(t *testing.T) {
+	const src = `
+vector#1cb5c415 {t:Type} # [ t ] = Vector t;
+true#3fedd339 = True;
+inputPhotoEmpty#1cd7bf0d = InputPhoto;
+user#d23c81a3 flags:# id:int bot:flags.3?true photos:Vector<inputPhotoEmpty> = User;
+---functions---
+messages.getUsers#2d84d5f5 {X:Type} ids:%(Vector int) query:!X = Vector<User>;
+`
+
+	schema, err := NewSchema(parseString(t, src))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	user := schema.ConstructorByID(0xd23c81a3)
+	if user == nil || user.Name() != "user" {
+		t.Fatalf("bad constructor for #d23c81a3: %v", user)
+	}
+
+	tests := []struct {
+		field *Field
+		name  string
+		typ   string
+		bare  bool
+	}{
+		{user.Fields[0], "flags", "#", true},
+		{user.Fields[1], "id", "int", true},
+		{user.Fields[2], "bot", "true", true},
+		{user.Fields[3], "photos", "Vector<inputPhotoEmpty>", false},
+	}
+	if len(user.Fields) != len(tests) {
+		t.Fatalf("expected %d fields, got %d", len(tests), len(user.Fields))
+	}
+	for i, tt := range tests {
+		if tt.field.Name != tt.name || tt.field.Type.String() != tt.typ || tt.field.Type.Bare() != tt.bare {
+			t.Errorf("<%d> got field %s:%v (bare: %v), expected %s:%s (bare: %v)",
+				i, tt.field.Name, tt.field.Type, tt.field.Type.Bare(), tt.name, tt.typ, tt.bare)
+		}
+	}
+
+	if cond := user.Fields[2].Cond; cond == nil || cond.Field != user.Fields[0] || cond.Bit != 3 {
+		t.Errorf("bad condition of bot: %+v", cond)
+	}
+	if !user.Fields[3].Type.Args[0].Bare() {
+		t.Errorf("inputPhotoEmpty is not bare")
+	}
+
+	vector := schema.Constructor("vector")
+	if !vector.Fields[0].Optional || vector.Fields[0].Type.Object != TypeType {
+		t.Errorf("bad optional field of vector: %+v", vector.Fields[0])
+	}
+	if rep := vector.Fields[2].Type; len(rep.Fields) != 1 || rep.Fields[0].Type.String() != "t" {
+		t.Errorf("bad repetition field of vector: %+v", rep)
+	}
+
+	f := schema.FunctionByID(0x2d84d5f5)
+	if f == nil || f.Name() != "messages.getUsers" || f.Namespace() != "messages" {
+		t.Fatalf("bad function for #2d84d5f5: %v", f)
+	}
+	if got := f.Result.String(); got != "Vector<User>" {
+		t.Errorf("bad result type: %s", got)
+	}
+	if ids := f.Fields[1]; ids.Type.String() != "%Vector<int>" || ids.Type.Boxing != BarePercent {
+		t.Errorf("bad field ids: %v", ids.Type)
+	}
+	query := f.Fields[2]
+	if v, ok := query.Type.Object.(*TypeVar); !query.Excl || !ok || v.Name() != "X" {
+		t.Errorf("bad field query: %+v", query)
+	}
+}
+
+func TestSchema_fieldsRepetitionCond(t *testing.T) {
+	const src = `
+matrix#1 flags:# n:# rows:n*[a:int b:flags.0?int c:a.1?int] = Matrix;
+`
+
+	schema, err := NewSchema(parseString(t, src))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m := schema.Constructor("matrix")
+	rows := m.Fields[2].Type.Fields
+	if cond := rows[1].Cond; cond == nil || cond.Field != m.Fields[0] || cond.Bit != 0 {
+		t.Errorf("bad condition of b: %+v", cond)
+	}
+	if cond := rows[2].Cond; cond == nil || cond.Field != rows[0] || cond.Bit != 1 {
+		t.Errorf("bad condition of c: %+v", cond)
+	}
+}
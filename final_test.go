@@ -0,0 +1,78 @@
+package tl
+
+import (
+	"bytes"
+	"testing"
+)
+
+func parseFileString(t *testing.T, filename, src string) *Program {
+	parser := NewFileParser(filename, bytes.NewBufferString(src))
+	program := parser.Parse()
+	if err := parser.Err(); err != nil {
+		t.Fatalf("parsing %s: %v", filename, err)
+	}
+	return program
+}
+
+func TestNewSchema_final(t *testing.T) {
+	tests := []struct {
+		files []string
+		errs  []string
+	}{
+		{[]string{"Empty False;"}, nil},
+		{[]string{"New Peer;\npeerUser id:int = Peer;", "peerChat id:int = Peer;"}, nil},
+		{[]string{"peerUser id:int = Peer;\nFinal Peer;"}, nil},
+		{
+			[]string{"Empty False;\nfalse = False;"},
+			[]string{"a.tl:2:1: cannot add constructor false to final type False, declared final at a.tl:1:1"},
+		},
+		{
+			[]string{"peerUser id:int = Peer;\nFinal Peer;", "peerChat id:int = Peer;"},
+			[]string{"b.tl:1:1: cannot add constructor peerChat to final type Peer, declared final at a.tl:2:1"},
+		},
+		{
+			[]string{"peerUser id:int = Peer;\n---types---\nFinal Peer;\npeerChat id:int = Peer;"},
+			[]string{"a.tl:4:1: cannot add constructor peerChat to final type Peer, declared final at a.tl:3:1"},
+		},
+		{
+			[]string{"peerUser id:int = Peer;", "New Peer;"},
+			[]string{"b.tl:1:1: New Peer: type Peer already declared at a.tl:1:19"},
+		},
+		{
+			[]string{"Empty Int;"},
+			[]string{"a.tl:1:1: Empty Int: type Int is predeclared"},
+		},
+		{
+			[]string{"Final Peer;\npeerUser id:int = Peer;"},
+			[]string{"a.tl:1:7: undefined: Peer"},
+		},
+	}
+
+	for i, tt := range tests {
+		var progs []*Program
+		for j, src := range tt.files {
+			progs = append(progs, parseFileString(t, string('a'+rune(j))+".tl", src))
+		}
+
+		_, err := NewSchema(progs...)
+
+		var got []string
+		if errs, ok := err.(ErrorList); ok {
+			for _, e := range errs {
+				got = append(got, e.Error())
+			}
+		} else if err != nil {
+			t.Fatalf("<%d> unexpected error %v", i, err)
+		}
+
+		if len(got) != len(tt.errs) {
+			t.Errorf("<%d> got errors %q, expected %q", i, got, tt.errs)
+			continue
+		}
+		for j := range got {
+			if got[j] != tt.errs[j] {
+				t.Errorf("<%d> got error %q, expected %q", i, got[j], tt.errs[j])
+			}
+		}
+	}
+}
@@ -0,0 +1,214 @@
+package tl
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// checkFlags checks the conditional fields of the combinators of s, e.g.
+// x:flags.3?int, including those of repetitions. The referenced field must be
+// declared earlier in the same combinator or an enclosing one with type # and
+// the bit must be in the range [0, 31].
+//
+// Fields sharing the same bit, bits of a flags field below its highest used
+// bit which no field uses and # fields used neither by conditional fields nor
+// by multiplicities are reported as soft errors, since they are legitimate
+// but often a mistake.
+func (s *Schema) checkFlags(errs *ErrorList) {
+	for _, c := range s.Constructors {
+		if d, ok := c.Decl.(*CombDecl); ok {
+			s.checkCombFlags(d, errs)
+		}
+	}
+	for _, f := range s.Functions {
+		s.checkCombFlags(f.Decl, errs)
+	}
+}
+
+// flagsField is a field visible to conditional fields: a field of the
+// combinator or of an enclosing repetition.
+type flagsField struct {
+	depth int // number of enclosing repetitions
+	index int // index in its argument list
+	arg   *Arg
+	name  *Ident
+}
+
+// flagsChecker checks the conditional fields of a single combinator.
+type flagsChecker struct {
+	s    *Schema
+	errs *ErrorList
+
+	nats []*Ident                     // named # fields in declaration order
+	used map[*Ident]bool              // # fields used by conditions or multiplicities
+	bits map[*Ident]map[uint64]*Ident // fields by flags field and bit, nil if anonymous
+}
+
+func (s *Schema) checkCombFlags(d *CombDecl, errs *ErrorList) {
+	c := &flagsChecker{
+		s:    s,
+		errs: errs,
+		used: make(map[*Ident]bool),
+		bits: make(map[*Ident]map[uint64]*Ident),
+	}
+	c.args(d.Args, nil, nil)
+
+	// # fields used by name, e.g. in n*[x:int] or in Tuple int n
+	vars := make(map[string]bool)
+	Inspect(d, func(n Node) bool {
+		if v, ok := n.(*Var); ok {
+			vars[v.Name] = true
+		}
+		return true
+	})
+
+	for _, name := range c.nats {
+		if !c.used[name] && !vars[name.Text()] {
+			errs.AddSoft(name.Pos(), fmt.Sprintf("field %s of type # is not used by any conditional field or repetition", name.Text()))
+		}
+		if bits := c.bits[name]; len(bits) > 0 {
+			c.unusedBits(name, bits)
+		}
+	}
+}
+
+// args checks the conditional fields of an argument list, the combinator's or
+// a repetition's. scope holds the fields of the enclosing lists and path the
+// indices of the enclosing repetitions in them.
+func (c *flagsChecker) args(args []*Arg, scope map[string]flagsField, path []int) {
+	depth := len(path)
+	fields := make(map[string]flagsField, len(scope)+len(args))
+	for name, f := range scope {
+		fields[name] = f
+	}
+	for i, arg := range args {
+		for _, name := range arg.Names {
+			if name.Name.Token == ItemUnderscore {
+				continue
+			}
+			if f, ok := fields[name.Text()]; ok && f.depth == depth {
+				continue
+			}
+			fields[name.Text()] = flagsField{depth, i, arg, name}
+			if c.s.isNat(arg.Type) {
+				c.nats = append(c.nats, name)
+			}
+		}
+	}
+
+	var last *Ident // last # field, the implicit multiplicity
+	for i, arg := range args {
+		if arg.Cond != nil {
+			c.cond(arg, i, fields, path)
+		}
+		if rep, ok := arg.Type.(*Repetition); ok {
+			if rep.Mult == nil && last != nil {
+				c.used[last] = true
+			}
+			c.args(rep.Args, fields, append(path, i))
+		}
+		if c.s.isNat(arg.Type) {
+			last = nil
+			if n := len(arg.Names); n > 0 {
+				last = arg.Names[n-1]
+			}
+		}
+	}
+}
+
+// cond checks the condition of the i-th argument of a list.
+func (c *flagsChecker) cond(arg *Arg, i int, fields map[string]flagsField, path []int) {
+	cond := arg.Cond
+	name := cond.Field.Text()
+	f, ok := fields[name]
+	if !ok {
+		c.errs.Add(cond.Field.Pos(), fmt.Sprintf("undefined flags field %s", name))
+		return
+	}
+	c.used[f.name] = true
+
+	// the index of the argument, or of its enclosing repetition, in the
+	// list of the flags field
+	index := i
+	if f.depth < len(path) {
+		index = path[f.depth]
+	}
+
+	switch {
+	case f.index >= index:
+		c.errs.Add(cond.Field.Pos(), fmt.Sprintf("flags field %s must be declared before %s", name, argName(arg)))
+		return
+	case !c.s.isNat(f.arg.Type):
+		c.errs.Add(cond.Field.Pos(), fmt.Sprintf("flags field %s is of type %s, not #", name, exprString(f.arg.Type)))
+		return
+	}
+
+	if cond.Bit == nil {
+		return
+	}
+
+	bit, err := strconv.ParseUint(cond.Bit.Value, 10, 64)
+	if err != nil || bit > 31 {
+		c.errs.Add(cond.Bit.Pos(), fmt.Sprintf("bit %s of %s out of range [0, 31]", cond.Bit.Value, name))
+		return
+	}
+
+	bits := c.bits[f.name]
+	if bits == nil {
+		bits = make(map[uint64]*Ident)
+		c.bits[f.name] = bits
+	}
+	prev, ok := bits[bit]
+	if ok && prev != nil && len(arg.Names) > 0 {
+		c.errs.AddSoft(cond.Bit.Pos(), fmt.Sprintf("%s shares bit %s.%d with %s declared at %v",
+			argName(arg), name, bit, prev.Text(), prev.Pos()))
+		return
+	}
+	if !ok || prev == nil {
+		bits[bit] = nil // used by an anonymous field
+		if len(arg.Names) > 0 {
+			bits[bit] = arg.Names[0]
+		}
+	}
+}
+
+// unusedBits reports the bits of the flags field below its highest used bit
+// which no conditional field uses.
+func (c *flagsChecker) unusedBits(flags *Ident, bits map[uint64]*Ident) {
+	var max uint64
+	for bit := range bits {
+		if bit > max {
+			max = bit
+		}
+	}
+
+	var unused []string
+	for bit := uint64(0); bit < max; bit++ {
+		if _, ok := bits[bit]; !ok {
+			unused = append(unused, strconv.FormatUint(bit, 10))
+		}
+	}
+
+	switch len(unused) {
+	case 0:
+	case 1:
+		c.errs.AddSoft(flags.Pos(), fmt.Sprintf("bit %s of %s is not used", unused[0], flags.Text()))
+	default:
+		c.errs.AddSoft(flags.Pos(), fmt.Sprintf("bits %s of %s are not used", strings.Join(unused, ", "), flags.Text()))
+	}
+}
+
+// isNat reports whether x is the type #.
+func (s *Schema) isNat(x Expr) bool {
+	ident, ok := x.(*TypeIdent)
+	return ok && s.Uses[ident] == Nat
+}
+
+// argName returns the name of an argument for error messages.
+func argName(arg *Arg) string {
+	if len(arg.Names) == 0 {
+		return "_"
+	}
+	return identList(arg.Names)
+}
@@ -0,0 +1,92 @@
+package tl
+
+import "testing"
+
+func TestChecker_flags(t *testing.T) {
+	tests := []struct {
+		src  string
+		errs []string
+	}{
+		{"user flags:# id:int name:flags.0?string photo:flags.1?int bot:flags.2?long = User;", nil},
+		{"user flags:# flags2:# name:flags.0?string bot:flags2.0?long = User;", nil},
+		{
+			"user id:int name:flags.0?string = User;",
+			[]string{"1:18: undefined flags field flags"},
+		},
+		{
+			"user name:flags.0?string flags:# = User;",
+			[]string{"1:11: flags field flags must be declared before name"},
+		},
+		{
+			"user flags:int name:flags.0?string = User;",
+			[]string{"1:21: flags field flags is of type int, not #"},
+		},
+		{
+			"user flags:# name:flags.32?string = User;",
+			[]string{"1:25: bit 32 of flags out of range [0, 31]"},
+		},
+		{
+			"user flags:# name:flags.0?string photo:flags.0?int = User;",
+			[]string{"1:46: warning: photo shares bit flags.0 with name declared at 1:14"},
+		},
+		{
+			"user flags:# flags2:# name:flags.0?string = User;",
+			[]string{"1:14: warning: field flags2 of type # is not used by any conditional field or repetition"},
+		},
+		{"user mask:# name:mask.0?string = User;", nil},
+		{
+			"user mask:# id:int = User;",
+			[]string{"1:6: warning: field mask of type # is not used by any conditional field or repetition"},
+		},
+		{"user flagsCount:# items:flagsCount*[int] = User;", nil},
+		{"vec n:# [int] = Vec;", nil},
+		{
+			"user flags:# a:flags.0?int b:flags.3?int = User;",
+			[]string{"1:6: warning: bits 1, 2 of flags are not used"},
+		},
+		{
+			"user flags:# a:flags.0?int b:flags.2?int = User;",
+			[]string{"1:6: warning: bit 1 of flags is not used"},
+		},
+
+		// repetitions
+		{"user flags:# rows:2*[x:flags.0?int] = User;", nil},
+		{"user n:# rows:n*[flags:# x:flags.0?int y:flags.1?long] = User;", nil},
+		{
+			"user rows:2*[x:flags.0?int] = User;",
+			[]string{"1:16: undefined flags field flags"},
+		},
+		{
+			"user rows:2*[x:flags.0?int] flags:# = User;",
+			[]string{"1:16: flags field flags must be declared before x"},
+		},
+		{
+			"user rows:2*[flags:# x:flags.0?int y:flags.0?long] = User;",
+			[]string{"1:44: warning: y shares bit flags.0 with x declared at 1:22"},
+		},
+	}
+
+	var checker Checker
+	for i, tt := range tests {
+		errs := checker.Check(parseString(t, tt.src))
+
+		var got []string
+		for _, err := range errs {
+			got = append(got, err.Error())
+		}
+		if len(got) != len(tt.errs) {
+			t.Errorf("<%d> %q: got errors %q, expected %q", i, tt.src, got, tt.errs)
+			continue
+		}
+		for j := range got {
+			if got[j] != tt.errs[j] {
+				t.Errorf("<%d> %q: got error %q, expected %q", i, tt.src, got[j], tt.errs[j])
+			}
+		}
+	}
+
+	errs := checker.Check(parseString(t, "user flags:# = User;"))
+	if len(errs) != 1 || !errs[0].Soft || errs.Err() != nil {
+		t.Errorf("expected a soft error for unused flags field, got %v", errs)
+	}
+}
@@ -0,0 +1,3 @@
+module github.com/igungor/tl
+
+go 1.16
@@ -0,0 +1,180 @@
+package tl
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Instance represents a type specialised by a partial application
+// declaration, e.g. `Vector int;` or `Pair Int String;`. The fields of its
+// constructors have the type parameters substituted with the arguments.
+type Instance struct {
+	Type         *Type
+	Args         []*TypeRef
+	Constructors []*InstanceConstructor
+
+	Decl Declaration // *PartialTypeAppDecl or *PartialCombAppDecl
+}
+
+// InstanceConstructor represents a constructor of an instance. Optional
+// fields bound by the arguments are omitted.
+type InstanceConstructor struct {
+	Constructor *Constructor
+	Fields      []*Field
+}
+
+// Name returns the name of the instance in the angle bracket form, e.g.
+// Vector<int>. Instances of partial combinator applications are named after
+// the constructor, e.g. vector<int>.
+func (in *Instance) Name() string {
+	name := in.Type.name
+	if _, ok := in.Decl.(*PartialCombAppDecl); ok {
+		name = in.Constructors[0].Constructor.name
+	}
+
+	args := make([]string, len(in.Args))
+	for i, arg := range in.Args {
+		args[i] = arg.String()
+	}
+	return name + "<" + strings.Join(args, ",") + ">"
+}
+
+// Instance returns the instance with the given name, e.g. Vector<int>, or
+// nil.
+func (s *Schema) Instance(name string) *Instance {
+	for _, in := range s.Instances {
+		if in.Name() == name {
+			return in
+		}
+	}
+	return nil
+}
+
+// instantiate expands the partial application declarations of prog.
+// Undefined heads are reported by the resolver.
+func (s *Schema) instantiate(prog *Program, errs *ErrorList) {
+	for _, decls := range [][]Declaration{prog.Constructors, prog.Types} {
+		for _, decl := range decls {
+			switch d := decl.(type) {
+			case *PartialTypeAppDecl:
+				t, ok := s.Uses[d.Name].(*Type)
+				if !ok {
+					continue
+				}
+				s.addInstance(d, t, t.Constructors, d.Args, errs)
+
+			case *PartialCombAppDecl:
+				c, ok := s.Lookup(d.Id.Id.Text()).(*Constructor)
+				if !ok || c.Decl == nil {
+					continue
+				}
+				s.addInstance(d, c.Type, []*Constructor{c}, d.Args, errs)
+			}
+		}
+	}
+}
+
+func (s *Schema) addInstance(decl Declaration, t *Type, cons []*Constructor, args []Expr, errs *ErrorList) {
+	in := &Instance{Type: t, Decl: decl}
+
+	for _, c := range cons {
+		d, ok := c.Decl.(*CombDecl)
+		if !ok {
+			continue
+		}
+
+		// partial applications may bind only the leading parameters
+		params := d.Result.Args
+		if len(params) == 0 {
+			errs.Add(decl.Pos(), fmt.Sprintf("cannot apply %s: type has no parameters", c.Type.name))
+			return
+		}
+		if len(args) == 0 || len(args) > len(params) {
+			errs.Add(decl.Pos(), fmt.Sprintf("wrong number of arguments for %s: got %d, expected 1 to %d",
+				c.Type.name, len(args), len(params)))
+			return
+		}
+
+		env := make(map[Object]*TypeRef)
+		for i, arg := range args {
+			v, ok := params[i].(*Var)
+			if !ok {
+				continue
+			}
+			if obj := s.Uses[v]; obj != nil {
+				env[obj] = s.typeRef(arg)
+			}
+		}
+
+		in.Constructors = append(in.Constructors, &InstanceConstructor{
+			Constructor: c,
+			Fields:      substituteFields(c.Fields, env),
+		})
+	}
+	if len(in.Constructors) == 0 {
+		errs.Add(decl.Pos(), fmt.Sprintf("cannot instantiate %s: no constructors", t.name))
+		return
+	}
+
+	for _, arg := range args {
+		in.Args = append(in.Args, s.typeRef(arg))
+	}
+	s.Instances = append(s.Instances, in)
+}
+
+// substituteFields returns copies of fields with the type variables bound in
+// env replaced. Optional fields bound in env are omitted.
+func substituteFields(fields []*Field, env map[Object]*TypeRef) []*Field {
+	bound := make(map[string]bool)
+	for obj := range env {
+		bound[obj.Name()] = true
+	}
+
+	copies := make(map[*Field]*Field)
+	var result []*Field
+	for _, f := range fields {
+		if f.Optional && bound[f.Name] {
+			continue
+		}
+
+		nf := *f
+		nf.Type = substitute(f.Type, env)
+		if f.Cond != nil {
+			cond := *f.Cond
+			if c, ok := copies[f.Cond.Field]; ok {
+				cond.Field = c
+			}
+			nf.Cond = &cond
+		}
+		copies[f] = &nf
+		result = append(result, &nf)
+	}
+	return result
+}
+
+// substitute returns a copy of ref with the type variables bound in env
+// replaced.
+func substitute(ref *TypeRef, env map[Object]*TypeRef) *TypeRef {
+	if ref == nil {
+		return nil
+	}
+
+	if arg, ok := env[ref.Object]; ok && ref.Object != nil {
+		r := *arg
+		if ref.Boxing == BarePercent && r.Boxing == Boxed {
+			r.Boxing = BarePercent
+		}
+		return &r
+	}
+
+	r := *ref
+	r.Args = nil
+	for _, arg := range ref.Args {
+		r.Args = append(r.Args, substitute(arg, env))
+	}
+	r.Mult = substitute(ref.Mult, env)
+	if ref.Fields != nil {
+		r.Fields = substituteFields(ref.Fields, env)
+	}
+	return &r
+}
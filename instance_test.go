@@ -0,0 +1,103 @@
+package tl
+
+import "testing"
+
+func TestSchema_Instances(t *testing.T) {
+	const src = `
+vector#1cb5c415 {t:Type} # [ t ] = Vector t;
+pair {X:Type} {Y:Type} a:X b:Y = Pair X Y;
+vectorTotal {t:Type} total_count:int vector:%(Vector t) = VectorTotal t;
+user id:int = User;
+
+Vector int;
+Pair Int String;
+VectorTotal User;
+vector long;
+`
+
+	schema, err := NewSchema(parseString(t, src))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var names []string
+	for _, in := range schema.Instances {
+		names = append(names, in.Name())
+	}
+	want := []string{"Vector<int>", "Pair<Int,String>", "VectorTotal<User>", "vector<long>"}
+	if len(names) != len(want) {
+		t.Fatalf("got instances %q, expected %q", names, want)
+	}
+	for i := range want {
+		if names[i] != want[i] {
+			t.Errorf("<%d> got instance %s, expected %s", i, names[i], want[i])
+		}
+	}
+
+	tests := []struct {
+		instance string
+		fields   []string
+	}{
+		{"Vector<int>", []string{":#", ":[int]"}},
+		{"Pair<Int,String>", []string{"a:Int", "b:String"}},
+		{"VectorTotal<User>", []string{"total_count:int", "vector:%Vector<User>"}},
+		{"vector<long>", []string{":#", ":[long]"}},
+	}
+	for _, tt := range tests {
+		in := schema.Instance(tt.instance)
+		if in == nil {
+			t.Errorf("no instance %s", tt.instance)
+			continue
+		}
+
+		var got []string
+		for _, f := range in.Constructors[0].Fields {
+			got = append(got, f.Name+":"+f.Type.String())
+		}
+		if len(got) != len(tt.fields) {
+			t.Errorf("%s: got fields %q, expected %q", tt.instance, got, tt.fields)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.fields[i] {
+				t.Errorf("%s: got field %s, expected %s", tt.instance, got[i], tt.fields[i])
+			}
+		}
+	}
+
+	// the element type of the repetition is substituted as well
+	rep := schema.Instance("Vector<int>").Constructors[0].Fields[1].Type
+	if elem := rep.Fields[0].Type; elem.String() != "int" || elem.Boxing != BareConstructor {
+		t.Errorf("bad element type of Vector<int>: %v (%v)", elem, elem.Boxing)
+	}
+}
+
+func TestSchema_InstanceErrors(t *testing.T) {
+	const src = `
+vector#1cb5c415 {t:Type} # [ t ] = Vector t;
+user id:int = User;
+Vector int long;
+User int;
+Photo int;
+`
+
+	_, err := NewSchema(parseString(t, src))
+	errs, ok := err.(ErrorList)
+	if !ok {
+		t.Fatalf("expected ErrorList, got %v", err)
+	}
+
+	want := []string{
+		"4:1: wrong number of arguments for Vector: got 2, expected 1 to 1",
+		"5:1: cannot apply User: type has no parameters",
+		"6:1: undefined: Photo",
+	}
+	if len(errs) != len(want) {
+		t.Fatalf("got errors %v, expected %q", errs, want)
+	}
+	for i := range want {
+		if errs[i].Error() != want[i] {
+			t.Errorf("<%d> got error %q, expected %q", i, errs[i].Error(), want[i])
+		}
+	}
+}
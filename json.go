@@ -0,0 +1,191 @@
+package tl
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+	"strings"
+)
+
+// The JSON representation of a schema follows the format of the schema
+// published by Telegram, with ids as signed decimal strings, extended with
+// the type numbers:
+//
+//   {
+//     "constructors": [{"id": "-1132882121", "predicate": "boolFalse", "params": [], "type": "Bool"}],
+//     "methods": [{"id": "1461180992", "method": "auth.logOut", "params": [], "type": "Bool"}],
+//     "types": [{"id": "1441533164", "name": "Bool", "constructors": ["boolFalse", "boolTrue"]}]
+//   }
+//
+type jsonSchema struct {
+	Constructors []jsonCombinator `json:"constructors"`
+	Methods      []jsonCombinator `json:"methods"`
+	Types        []jsonType       `json:"types"`
+}
+
+type jsonCombinator struct {
+	ID        string      `json:"id"`
+	Predicate string      `json:"predicate,omitempty"`
+	Method    string      `json:"method,omitempty"`
+	Params    []jsonParam `json:"params"`
+	Type      string      `json:"type"`
+}
+
+type jsonParam struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+type jsonType struct {
+	ID           string   `json:"id"`
+	Name         string   `json:"name"`
+	Constructors []string `json:"constructors"`
+}
+
+// MarshalJSON implements the json.Marshaler interface.
+func (s *Schema) MarshalJSON() ([]byte, error) {
+	v := jsonSchema{
+		Constructors: []jsonCombinator{},
+		Methods:      []jsonCombinator{},
+		Types:        []jsonType{},
+	}
+
+	for _, c := range s.Constructors {
+		jc := jsonCombinator{ID: jsonID(c.ID), Predicate: c.Name(), Params: []jsonParam{}, Type: c.Type.Name()}
+		if d, ok := c.Decl.(*CombDecl); ok {
+			jc.Params = jsonParams(d.Args)
+			jc.Type = resultString(d.Result)
+		}
+		v.Constructors = append(v.Constructors, jc)
+	}
+
+	for _, f := range s.Functions {
+		v.Methods = append(v.Methods, jsonCombinator{
+			ID:     jsonID(f.ID),
+			Method: f.Name(),
+			Params: jsonParams(f.Decl.Args),
+			Type:   resultString(f.Decl.Result),
+		})
+	}
+
+	for _, t := range s.Types {
+		jt := jsonType{ID: jsonID(t.ID()), Name: t.Name(), Constructors: []string{}}
+		for _, c := range t.Constructors {
+			jt.Constructors = append(jt.Constructors, c.Name())
+		}
+		v.Types = append(v.Types, jt)
+	}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+// jsonID formats a combinator-name or a type number as a signed decimal.
+func jsonID(id uint32) string {
+	return strconv.FormatInt(int64(int32(id)), 10)
+}
+
+func jsonParams(args []*Arg) []jsonParam {
+	params := []jsonParam{}
+	for _, arg := range args {
+		typ := argTypeString(arg)
+		if len(arg.Names) == 0 {
+			params = append(params, jsonParam{Type: typ})
+			continue
+		}
+		for _, name := range arg.Names {
+			params = append(params, jsonParam{Name: name.Text(), Type: typ})
+		}
+	}
+	return params
+}
+
+// argTypeString returns the type of arg including its condition, e.g.
+// flags.0?Vector<int>.
+func argTypeString(arg *Arg) string {
+	var b strings.Builder
+	if c := arg.Cond; c != nil {
+		b.WriteString(c.Field.Text())
+		if c.Bit != nil {
+			b.WriteString(".")
+			b.WriteString(c.Bit.Value)
+		}
+		b.WriteString("?")
+	}
+	if arg.Excl.IsValid() {
+		b.WriteString("!")
+	}
+	b.WriteString(exprString(arg.Type))
+	return b.String()
+}
+
+// resultString returns the result type as written, e.g. Vector t or
+// Vector<User>.
+func resultString(r *ResultType) string {
+	if len(r.Args) == 0 {
+		return r.Name.Name
+	}
+
+	args := make([]string, len(r.Args))
+	for i, x := range r.Args {
+		args[i] = exprString(x)
+	}
+
+	if r.Lang.IsValid() {
+		return r.Name.Name + "<" + strings.Join(args, ",") + ">"
+	}
+	return r.Name.Name + " " + strings.Join(args, " ")
+}
+
+// exprString returns the text of a type expression with type applications
+// written in the angle bracket form, e.g. Vector<int>.
+func exprString(x Expr) string {
+	switch x := x.(type) {
+	case *TypeIdent:
+		return x.Name
+	case *Var:
+		return x.Name
+	case *NatConst:
+		return x.Value
+	case *BareType:
+		return "%" + exprString(x.X)
+	case *ParenExpr:
+		if _, ok := x.X.(*AppExpr); ok {
+			return exprString(x.X)
+		}
+		return "(" + exprString(x.X) + ")"
+	case *AppExpr:
+		args := make([]string, len(x.Args))
+		for i, arg := range x.Args {
+			args[i] = exprString(arg)
+		}
+		return exprString(x.Fun) + "<" + strings.Join(args, ",") + ">"
+	case *SumExpr:
+		return exprString(x.X) + "+" + exprString(x.Y)
+	case *Repetition:
+		var b strings.Builder
+		if x.Mult != nil {
+			b.WriteString(exprString(x.Mult))
+			b.WriteString("*")
+		}
+		b.WriteString("[")
+		for i, arg := range x.Args {
+			if i > 0 {
+				b.WriteString(" ")
+			}
+			if len(arg.Names) > 0 {
+				b.WriteString(identList(arg.Names))
+				b.WriteString(":")
+			}
+			b.WriteString(argTypeString(arg))
+		}
+		b.WriteString("]")
+		return b.String()
+	}
+	return ""
+}
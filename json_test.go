@@ -0,0 +1,52 @@
+package tl
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestSchema_MarshalJSON(t *testing.T) {
+	const src = `
+boolFalse#bc799737 = Bool;
+boolTrue#997275b5 = Bool;
+vector#1cb5c415 {t:Type} # [ t ] = Vector t;
+true#3fedd339 = True;
+userEmpty#200250ba id:int = User;
+---functions---
+auth.logOut#5717da40 = Bool;
+getUsers#2d84d5f5 id:Vector<int> flags:# silent:flags.0?true = Vector User;
+`
+
+	schema, err := NewSchema(parseString(t, src))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(schema); err != nil {
+		t.Fatal(err)
+	}
+
+	const want = `{"constructors":[` +
+		`{"id":"-1132882121","predicate":"boolFalse","params":[],"type":"Bool"},` +
+		`{"id":"-1720552011","predicate":"boolTrue","params":[],"type":"Bool"},` +
+		`{"id":"481674261","predicate":"vector","params":[{"name":"","type":"#"},{"name":"","type":"[t]"}],"type":"Vector t"},` +
+		`{"id":"1072550713","predicate":"true","params":[],"type":"True"},` +
+		`{"id":"537022650","predicate":"userEmpty","params":[{"name":"id","type":"int"}],"type":"User"}],` +
+		`"methods":[` +
+		`{"id":"1461180992","method":"auth.logOut","params":[],"type":"Bool"},` +
+		`{"id":"763680245","method":"getUsers","params":[{"name":"id","type":"Vector<int>"},{"name":"flags","type":"#"},{"name":"silent","type":"flags.0?true"}],"type":"Vector User"}],` +
+		`"types":[` +
+		`{"id":"1441533164","name":"Bool","constructors":["boolFalse","boolTrue"]},` +
+		`{"id":"481674261","name":"Vector","constructors":["vector"]},` +
+		`{"id":"1072550713","name":"True","constructors":["true"]},` +
+		`{"id":"537022650","name":"User","constructors":["userEmpty"]}]}`
+
+	if got := strings.TrimSpace(buf.String()); got != want {
+		t.Errorf("bad JSON:\ngot  %s\nwant %s", got, want)
+	}
+}
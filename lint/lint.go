@@ -0,0 +1,144 @@
+// Package lint implements style and safety checks of TL schemas which are not
+// errors by themselves, e.g. ids stored in 32-bit fields or unused types.
+//
+// Each check is a Rule which can be enabled or disabled by a Config, usually
+// loaded from a JSON file:
+//
+//   {
+//     "rules": {
+//       "id-long": false,
+//       "go-keyword": true
+//     }
+//   }
+//
+// Rules not mentioned in the config are enabled.
+package lint
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"sort"
+
+	"github.com/igungor/tl"
+)
+
+// A Rule is a single check.
+type Rule struct {
+	Name  string // name used in configs and findings, e.g. camel-case
+	Doc   string // one line description
+	Check func(*Pass)
+}
+
+// A Pass provides a rule with the checked schema and collects its findings.
+type Pass struct {
+	Programs []*tl.Program
+	Schema   *tl.Schema
+
+	rule     *Rule
+	findings *[]Finding
+}
+
+// Reportf reports a finding at pos.
+func (p *Pass) Reportf(pos tl.Pos, format string, args ...interface{}) {
+	*p.findings = append(*p.findings, Finding{
+		Pos:  pos,
+		Rule: p.rule.Name,
+		Msg:  fmt.Sprintf(format, args...),
+	})
+}
+
+// decls calls fn for each declaration of the programs.
+func (p *Pass) decls(fn func(decl tl.Declaration, function bool)) {
+	for _, prog := range p.Programs {
+		for _, decl := range prog.Constructors {
+			fn(decl, false)
+		}
+		for _, decl := range prog.Types {
+			fn(decl, false)
+		}
+		for _, decl := range prog.Functions {
+			fn(decl, true)
+		}
+	}
+}
+
+// A Finding is a problem reported by a rule.
+type Finding struct {
+	Pos  tl.Pos
+	Rule string
+	Msg  string
+}
+
+func (f Finding) String() string {
+	return fmt.Sprintf("%v: %s (%s)", f.Pos, f.Msg, f.Rule)
+}
+
+// Config enables and disables rules by name.
+type Config struct {
+	Rules map[string]bool `json:"rules"`
+}
+
+// LoadConfig reads a JSON config from the named file. Unknown rules are
+// reported as errors.
+func LoadConfig(filename string) (*Config, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	var cfg Config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("%s: %v", filename, err)
+	}
+	for name := range cfg.Rules {
+		if Lookup(name) == nil {
+			return nil, fmt.Errorf("%s: unknown rule %q", filename, name)
+		}
+	}
+	return &cfg, nil
+}
+
+// Enabled reports whether the named rule is enabled. A nil config enables all
+// rules.
+func (cfg *Config) Enabled(name string) bool {
+	if cfg == nil {
+		return true
+	}
+	enabled, ok := cfg.Rules[name]
+	return !ok || enabled
+}
+
+// Lookup returns the rule with the given name, or nil.
+func Lookup(name string) *Rule {
+	for _, r := range Rules {
+		if r.Name == name {
+			return r
+		}
+	}
+	return nil
+}
+
+// Lint runs the rules enabled by cfg over the given programs, the files of a
+// single schema, and returns the findings sorted by position. The schema must
+// be free of errors.
+func Lint(cfg *Config, progs ...*tl.Program) ([]Finding, error) {
+	schema, err := tl.NewSchema(progs...)
+	if err != nil {
+		return nil, err
+	}
+
+	var findings []Finding
+	for _, rule := range Rules {
+		if !cfg.Enabled(rule.Name) {
+			continue
+		}
+		rule.Check(&Pass{Programs: progs, Schema: schema, rule: rule, findings: &findings})
+	}
+
+	order := tl.FileOrder(tl.Filenames(progs...))
+	sort.SliceStable(findings, func(i, j int) bool {
+		return order(findings[i].Pos, findings[j].Pos)
+	})
+	return findings, nil
+}
@@ -0,0 +1,155 @@
+package lint
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/igungor/tl"
+)
+
+func parse(t *testing.T, src string) *tl.Program {
+	parser := tl.NewParser(bytes.NewBufferString(src))
+	program := parser.Parse()
+	if err := parser.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return program
+}
+
+func TestRules(t *testing.T) {
+	tests := []struct {
+		rule     string
+		src      string
+		findings []string
+	}{
+		{
+			"camel-case",
+			"user_full#1 id:long = UserFull;\nphotos.photo#2 id:long = photos.Photo_Info;\n---functions---\ngetUserFull#3 = UserFull;",
+			[]string{
+				"1:1: constructor name user_full is not lowerCamelCase (camel-case)",
+				"2:26: type name photos.Photo_Info is not UpperCamelCase (camel-case)",
+			},
+		},
+		{
+			"id-long",
+			"user#1 id:int user_id:long chat_id:int count:int = User;",
+			[]string{
+				"1:8: field id of user is an int, use long for ids (id-long)",
+				"1:28: field chat_id of user is an int, use long for ids (id-long)",
+			},
+		},
+		{
+			"bare-result",
+			"vector#1cb5c415 {t:Type} # [ t ] = Vector t;\nuser#1 id:long = User;\n---functions---\ngetUsers#2 = Vector<user>;\ngetIds#3 = Vector<long>;\ngetAll#4 = Vector<%User>;",
+			[]string{
+				"4:21: getUsers returns the bare type user (bare-result)",
+				"6:19: getAll returns a bare type (bare-result)",
+			},
+		},
+		{
+			"unused-type",
+			"int ? = Int;\nuser#1 id:int = User;\nphoto#2 id:long = Photo;\nchat#3 admin:User = Chat;\n---functions---\ngetChat#4 = Chat;",
+			[]string{"3:19: type Photo is unused (unused-type)"},
+		},
+		{
+			"explicit-id",
+			"user id:long = User;\npeerUser id:long = Peer;\npeerChat id:long = Peer;\nphoto#2 id:long = Photo;",
+			[]string{"1:1: user is the only constructor of User and has no explicit id (explicit-id)"},
+		},
+		{
+			"go-keyword",
+			"user#1 {type:Type} id:long range:string func:type = User;",
+			[]string{
+				"1:9: field type of user is a Go keyword (go-keyword)",
+				"1:28: field range of user is a Go keyword (go-keyword)",
+				"1:41: field func of user is a Go keyword (go-keyword)",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		cfg := &Config{Rules: make(map[string]bool)}
+		for _, r := range Rules {
+			cfg.Rules[r.Name] = r.Name == tt.rule
+		}
+
+		findings, err := Lint(cfg, parse(t, tt.src))
+		if err != nil {
+			t.Errorf("%s: %v", tt.rule, err)
+			continue
+		}
+
+		if len(findings) != len(tt.findings) {
+			t.Errorf("%s: got findings %v, expected %q", tt.rule, findings, tt.findings)
+			continue
+		}
+		for i, f := range findings {
+			if f.String() != tt.findings[i] {
+				t.Errorf("%s: got finding %q, expected %q", tt.rule, f.String(), tt.findings[i])
+			}
+		}
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lint")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	filename := filepath.Join(dir, "lint.json")
+	if err := ioutil.WriteFile(filename, []byte(`{"rules": {"id-long": false, "go-keyword": true}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := LoadConfig(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Enabled("id-long") || !cfg.Enabled("go-keyword") || !cfg.Enabled("camel-case") {
+		t.Errorf("bad config: %+v", cfg)
+	}
+
+	if err := ioutil.WriteFile(filename, []byte(`{"rules": {"no-such-rule": false}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadConfig(filename); err == nil {
+		t.Errorf("expected error for unknown rule")
+	}
+}
+
+func TestLint_fileOrder(t *testing.T) {
+	var progs []*tl.Program
+	for _, file := range []struct{ name, src string }{
+		{"b.tl", "user_full#1 id:long = UserFull;"},
+		{"a.tl", "chat_full#2 id:long = ChatFull;"},
+	} {
+		parser := tl.NewFileParser(file.name, bytes.NewBufferString(file.src))
+		progs = append(progs, parser.Parse())
+		if err := parser.Err(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	findings, err := Lint(&Config{Rules: map[string]bool{}}, progs...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, f := range findings {
+		if f.Rule == "camel-case" {
+			got = append(got, f.String())
+		}
+	}
+	want := []string{
+		"b.tl:1:1: constructor name user_full is not lowerCamelCase (camel-case)",
+		"a.tl:1:1: constructor name chat_full is not lowerCamelCase (camel-case)",
+	}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("got findings %q, expected %q", got, want)
+	}
+}
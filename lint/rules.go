@@ -0,0 +1,206 @@
+package lint
+
+import (
+	"go/token"
+	"regexp"
+	"strings"
+
+	"github.com/igungor/tl"
+)
+
+// Rules lists all available rules.
+var Rules = []*Rule{
+	{
+		Name:  "camel-case",
+		Doc:   "constructor and function names are lowerCamelCase, type names UpperCamelCase",
+		Check: checkCamelCase,
+	},
+	{
+		Name:  "id-long",
+		Doc:   "id fields are of type long rather than int",
+		Check: checkIDLong,
+	},
+	{
+		Name:  "bare-result",
+		Doc:   "functions don't return bare types",
+		Check: checkBareResult,
+	},
+	{
+		Name:  "unused-type",
+		Doc:   "types are used by a field or a function",
+		Check: checkUnusedType,
+	},
+	{
+		Name:  "explicit-id",
+		Doc:   "constructors of types with a single constructor have an explicit id",
+		Check: checkExplicitID,
+	},
+	{
+		Name:  "go-keyword",
+		Doc:   "field names are not Go keywords",
+		Check: checkGoKeyword,
+	},
+}
+
+var (
+	lowerCamelCase = regexp.MustCompile(`^[a-z][a-zA-Z0-9]*$`)
+	upperCamelCase = regexp.MustCompile(`^[A-Z][a-zA-Z0-9]*$`)
+)
+
+// localName returns name without its namespace.
+func localName(name string) string {
+	return name[strings.LastIndexByte(name, '.')+1:]
+}
+
+func checkCamelCase(p *Pass) {
+	for _, c := range p.Schema.Constructors {
+		if c.Decl != nil && !lowerCamelCase.MatchString(localName(c.Name())) {
+			p.Reportf(c.Pos(), "constructor name %s is not lowerCamelCase", c.Name())
+		}
+	}
+	for _, f := range p.Schema.Functions {
+		if !lowerCamelCase.MatchString(localName(f.Name())) {
+			p.Reportf(f.Decl.Pos(), "function name %s is not lowerCamelCase", f.Name())
+		}
+	}
+	for _, t := range p.Schema.Types {
+		if !upperCamelCase.MatchString(localName(t.Name())) {
+			p.Reportf(t.Pos(), "type name %s is not UpperCamelCase", t.Name())
+		}
+	}
+}
+
+func checkIDLong(p *Pass) {
+	p.decls(func(decl tl.Declaration, _ bool) {
+		d, ok := decl.(*tl.CombDecl)
+		if !ok {
+			return
+		}
+		for _, arg := range d.Args {
+			typ, ok := arg.Type.(*tl.TypeIdent)
+			if !ok || typ.Name != "int" {
+				continue
+			}
+			for _, name := range arg.Names {
+				if n := name.Text(); n == "id" || strings.HasSuffix(n, "_id") {
+					p.Reportf(name.Pos(), "field %s of %s is an int, use long for ids", n, d.Name())
+				}
+			}
+		}
+	})
+}
+
+func checkBareResult(p *Pass) {
+	for _, f := range p.Schema.Functions {
+		for _, arg := range f.Decl.Result.Args {
+			tl.Inspect(arg, func(n tl.Node) bool {
+				switch n := n.(type) {
+				case *tl.BareType:
+					p.Reportf(n.Pos(), "%s returns a bare type", f.Name())
+					return false
+				case *tl.TypeIdent:
+					// builtins such as int are bare by nature
+					if c, ok := p.Schema.ObjectOf(n).(*tl.Constructor); ok {
+						if _, ok := c.Decl.(*tl.CombDecl); ok {
+							p.Reportf(n.Pos(), "%s returns the bare type %s", f.Name(), n.Name)
+						}
+					}
+				}
+				return true
+			})
+		}
+	}
+}
+
+func checkUnusedType(p *Pass) {
+	used := make(map[*tl.Type]bool)
+	use := func(n tl.Node) bool {
+		switch obj := p.Schema.ObjectOf(n).(type) {
+		case *tl.Type:
+			used[obj] = true
+		case *tl.Constructor:
+			used[obj.Type] = true
+		}
+		return true
+	}
+
+	p.decls(func(decl tl.Declaration, function bool) {
+		switch d := decl.(type) {
+		case *tl.CombDecl:
+			for _, arg := range d.OptArgs {
+				tl.Inspect(arg, use)
+			}
+			for _, arg := range d.Args {
+				tl.Inspect(arg, use)
+			}
+			// the result of a constructor declares its type
+			if function {
+				tl.Inspect(d.Result, use)
+			} else {
+				for _, arg := range d.Result.Args {
+					tl.Inspect(arg, use)
+				}
+			}
+		case *tl.PartialTypeAppDecl:
+			tl.Inspect(d, use)
+		}
+	})
+
+	for _, t := range p.Schema.Types {
+		if used[t] || isBuiltin(t) {
+			continue
+		}
+		p.Reportf(t.Pos(), "type %s is unused", t.Name())
+	}
+}
+
+// isBuiltin reports whether t is declared by builtin combinators only, e.g.
+// int ? = Int.
+func isBuiltin(t *tl.Type) bool {
+	for _, c := range t.Constructors {
+		if _, ok := c.Decl.(*tl.BuiltinCombDecl); !ok {
+			return false
+		}
+	}
+	return len(t.Constructors) > 0
+}
+
+func checkExplicitID(p *Pass) {
+	for _, t := range p.Schema.Types {
+		if len(t.Constructors) != 1 {
+			continue
+		}
+		c := t.Constructors[0]
+		d, ok := c.Decl.(*tl.CombDecl)
+		if !ok {
+			continue
+		}
+		if _, ok := d.Id.ID(); !ok {
+			p.Reportf(c.Pos(), "%s is the only constructor of %s and has no explicit id", c.Name(), t.Name())
+		}
+	}
+}
+
+func checkGoKeyword(p *Pass) {
+	p.decls(func(decl tl.Declaration, _ bool) {
+		d, ok := decl.(*tl.CombDecl)
+		if !ok {
+			return
+		}
+		tl.Inspect(d, func(n tl.Node) bool {
+			var names []*tl.Ident
+			switch n := n.(type) {
+			case *tl.Arg:
+				names = n.Names
+			case *tl.OptionalArg:
+				names = n.Names
+			}
+			for _, name := range names {
+				if token.IsKeyword(name.Text()) {
+					p.Reportf(name.Pos(), "field %s of %s is a Go keyword", name.Text(), d.Name())
+				}
+			}
+			return true
+		})
+	})
+}
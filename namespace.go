@@ -0,0 +1,148 @@
+package tl
+
+import (
+	"path"
+	"sort"
+	"strings"
+)
+
+// Namespace represents the types, constructors and functions sharing the same
+// namespace, e.g. messages for messages.Messages and messages.sendMessage.
+type Namespace struct {
+	Name         string // empty for the global namespace
+	Types        []*Type
+	Constructors []*Constructor
+	Functions    []*Function
+}
+
+// Namespace returns the namespace of the type, e.g. messages for
+// messages.Messages, or the empty string.
+func (t *Type) Namespace() string { return namespace(t.name) }
+
+// Namespace returns the namespace of the constructor, e.g. storage for
+// storage.fileJpeg, or the empty string.
+func (c *Constructor) Namespace() string { return namespace(c.name) }
+
+// Namespace returns the namespace of the function, e.g. auth for
+// auth.checkPhone, or the empty string.
+func (f *Function) Namespace() string { return namespace(f.name) }
+
+// namespace returns the namespace of an identifier without a
+// combinator-name.
+func namespace(name string) string {
+	if i := strings.LastIndexByte(name, '.'); i >= 0 {
+		return name[:i]
+	}
+	return ""
+}
+
+// Namespaces returns the namespaces of s sorted by name, the global namespace
+// first. Declarations keep their order within a namespace.
+func (s *Schema) Namespaces() []*Namespace {
+	byName := make(map[string]*Namespace)
+	lookup := func(name string) *Namespace {
+		ns, ok := byName[name]
+		if !ok {
+			ns = &Namespace{Name: name}
+			byName[name] = ns
+		}
+		return ns
+	}
+
+	for _, t := range s.Types {
+		ns := lookup(t.Namespace())
+		ns.Types = append(ns.Types, t)
+	}
+	for _, c := range s.Constructors {
+		ns := lookup(c.Namespace())
+		ns.Constructors = append(ns.Constructors, c)
+	}
+	for _, f := range s.Functions {
+		ns := lookup(f.Namespace())
+		ns.Functions = append(ns.Functions, f)
+	}
+
+	namespaces := make([]*Namespace, 0, len(byName))
+	for _, ns := range byName {
+		namespaces = append(namespaces, ns)
+	}
+	sort.Slice(namespaces, func(i, j int) bool {
+		return namespaces[i].Name < namespaces[j].Name
+	})
+	return namespaces
+}
+
+// Namespace returns the namespace with the given name, or nil if s declares
+// nothing in it.
+func (s *Schema) Namespace(name string) *Namespace {
+	for _, ns := range s.Namespaces() {
+		if ns.Name == name {
+			return ns
+		}
+	}
+	return nil
+}
+
+// Filter returns the schema of the types and functions of s whose names match
+// any of the given patterns, e.g. messages.* for the messages namespace. The
+// pattern syntax is the one of path.Match. Types keep all their constructors,
+// so that the type numbers don't change.
+func (s *Schema) Filter(patterns ...string) (*Schema, error) {
+	for _, pattern := range patterns {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, err
+		}
+	}
+	match := func(name string) bool {
+		for _, pattern := range patterns {
+			if ok, _ := path.Match(pattern, name); ok {
+				return true
+			}
+		}
+		return false
+	}
+
+	fs := &Schema{
+		Uses:           s.Uses,
+		types:          make(map[string]*Type),
+		constructors:   make(map[string]*Constructor),
+		functions:      make(map[string]*Function),
+		constructorIDs: make(map[uint32]*Constructor),
+		functionIDs:    make(map[uint32]*Function),
+		universe:       s.universe,
+	}
+
+	for _, t := range s.Types {
+		if !match(t.name) {
+			continue
+		}
+		fs.Types = append(fs.Types, t)
+		fs.types[t.name] = t
+		for _, c := range t.Constructors {
+			fs.Constructors = append(fs.Constructors, c)
+			fs.constructors[c.name] = c
+			if _, ok := fs.constructorIDs[c.ID]; !ok {
+				fs.constructorIDs[c.ID] = c
+			}
+		}
+	}
+
+	for _, in := range s.Instances {
+		if _, ok := fs.types[in.Type.name]; ok {
+			fs.Instances = append(fs.Instances, in)
+		}
+	}
+
+	for _, f := range s.Functions {
+		if !match(f.name) {
+			continue
+		}
+		fs.Functions = append(fs.Functions, f)
+		fs.functions[f.name] = f
+		if _, ok := fs.functionIDs[f.ID]; !ok {
+			fs.functionIDs[f.ID] = f
+		}
+	}
+
+	return fs, nil
+}
@@ -0,0 +1,79 @@
+package tl
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSchema_Namespaces(t *testing.T) {
+	const src = `
+boolFalse#bc799737 = Bool;
+contacts.found#566000e results:int = contacts.Found;
+messages.messages#8c718e87 count:int = messages.Messages;
+messages.messagesSlice#b446ae3 count:int = messages.Messages;
+---functions---
+messages.getHistory#92a1df2f offset:int = messages.Messages;
+contacts.search#11f812d8 q:string = contacts.Found;
+help.getSupport = Bool;
+`
+
+	schema, err := NewSchema(parseString(t, src))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for _, ns := range schema.Namespaces() {
+		got = append(got, ns.Name)
+	}
+	if want := []string{"", "contacts", "help", "messages"}; strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("got namespaces %q, expected %q", got, want)
+	}
+
+	ns := schema.Namespace("messages")
+	if ns == nil || len(ns.Types) != 1 || len(ns.Constructors) != 2 || len(ns.Functions) != 1 {
+		t.Fatalf("bad messages namespace: %+v", ns)
+	}
+	if schema.Namespace("photos") != nil {
+		t.Errorf("unexpected namespace photos")
+	}
+
+	filtered, err := schema.Filter("messages.*", "Bool")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(filtered.Types) != 2 || len(filtered.Constructors) != 3 || len(filtered.Functions) != 1 {
+		t.Errorf("bad filtered schema: %d types, %d constructors, %d functions",
+			len(filtered.Types), len(filtered.Constructors), len(filtered.Functions))
+	}
+	if filtered.Function("messages.getHistory") == nil || filtered.Function("contacts.search") != nil {
+		t.Errorf("bad filtered functions")
+	}
+
+	if _, err := schema.Filter("[messages"); err == nil {
+		t.Errorf("expected error for malformed pattern")
+	}
+}
+
+func TestToken_Namespace(t *testing.T) {
+	tests := []struct {
+		tok Token
+		ns  string
+	}{
+		{Token{ItemLowerIdent, "user"}, ""},
+		{Token{ItemLowerIdent, "messages.sendEncrypted"}, "messages"},
+		{Token{ItemLowerIdent, "users.user#decafbad"}, "users"},
+		{Token{ItemUpperIdent, "help.Support"}, "help"},
+		{Token{ItemUpperIdent, "User"}, ""},
+		{Token{ItemNatConst, "42"}, ""},
+	}
+
+	for _, tt := range tests {
+		if got := tt.tok.Namespace(); got != tt.ns {
+			t.Errorf("%s: got namespace %q, expected %q", tt.tok.Literal, got, tt.ns)
+		}
+		if got := tt.tok.HasNamespace(); got != (tt.ns != "") {
+			t.Errorf("%s: HasNamespace = %v", tt.tok.Literal, got)
+		}
+	}
+}
@@ -3,25 +3,59 @@ package tl
 import (
 	"fmt"
 	"io"
-	"log"
 )
 
+// Error describes a syntax or a semantic error at the given source position.
+type Error struct {
+	Pos  Pos
+	Msg  string
+	Soft bool // if set, the error is a warning rather than an error
+}
+
+func (e *Error) Error() string {
+	if e.Soft {
+		return fmt.Sprintf("%v: warning: %v", e.Pos, e.Msg)
+	}
+	return fmt.Sprintf("%v: %v", e.Pos, e.Msg)
+}
+
 // Parser holds the parser's internal state while consuming a given
 // token.
 type Parser struct {
 	s   *Scanner
 	tok Token // one token look-ahead
+	pos Pos   // position of tok
 	err error // sticky error
 
+	buf      []scannedToken // tokens read ahead by peek
+	comments []*Comment     // comments collected so far
+
+	// names of the fields declared so far by the combinator being parsed.
+	// references to these names are parsed as variables.
+	vars map[string]bool
+
 	Trace  bool // parsing mode
 	indent int  // indentation used for tracing output
 }
 
+type scannedToken struct {
+	tok Token
+	pos Pos
+}
+
 // NewParser returns a Parser from the given io.Reader.
 func NewParser(r io.Reader) *Parser {
 	return &Parser{s: NewScanner(r)}
 }
 
+// NewFileParser returns a new instance of Parser for the named file. The
+// positions of the nodes and errors include the filename.
+func NewFileParser(filename string, r io.Reader) *Parser {
+	p := NewParser(r)
+	p.s.pos.Filename = filename
+	return p
+}
+
 func (p *Parser) Err() error {
 	return p.err
 }
@@ -32,105 +66,108 @@ func (p *Parser) setErr(err error) {
 	}
 }
 
-// Parse is the entry-point to the parser.
+// Parse is the entry-point to the parser. It stops at the first error, which
+// is reported by Err.
 //
 // TL-program ::= constr-declarations { --- functions --- fun-declarations | --- types --- constr-declarations }
 //
-func (p *Parser) Parse() (program Program) {
+func (p *Parser) Parse() *Program {
 	defer un(trace(p, "ParseProgram"))
 
-	p.next()
-
-	// constr-declarations
-	for {
-		if p.tok.Token == ItemEOF {
-			return
-		}
+	program := &Program{Filename: p.s.pos.Filename}
+	decls := &program.Constructors
 
-		p.parseDecl()
-		p.expectSemi()
+	p.next()
 
+	for p.tok.Token != ItemEOF && p.err == nil {
 		if p.tok.Token == ItemTripleMinus {
-			if p.tok.Literal == "functions" {
-				p.next()
-				p.expect(ItemTripleMinus)
-				break
-			} else {
-				p.setErr(fmt.Errorf("expected functions separator"))
+			pos := p.pos
+			name := p.parseSeparator()
+			program.Separators = append(program.Separators, &Separator{Start: pos, Name: name})
+			switch name {
+			case "functions":
+				decls = &program.Functions
+				if !program.FunctionsSep.IsValid() {
+					program.FunctionsSep = pos
+				}
+			case "types":
+				decls = &program.Types
+				if !program.TypesSep.IsValid() {
+					program.TypesSep = pos
+				}
 			}
+			continue
 		}
-	}
 
-	// fun-declarations
-	for {
-		if p.tok.Token == ItemEOF {
-			return
-		}
-
-		p.parseDecl()
+		decl := p.parseDecl()
 		p.expectSemi()
 
-		if p.tok.Token == ItemTripleMinus {
-			if p.tok.Literal == "types" {
-				break
-			} else {
-				p.setErr(fmt.Errorf("expected types separator"))
-			}
+		if p.err == nil {
+			*decls = append(*decls, decl)
 		}
 	}
 
-	// types-declarations
-	for {
-		if p.tok.Token == ItemEOF {
-			return
-		}
-
-		p.parseDecl()
-		p.expectSemi()
+	program.Comments = p.comments
 
-		if p.tok.Token == ItemTripleMinus {
-			p.setErr(fmt.Errorf("unexpected separator"))
-		}
-	}
+	return program
 }
 
-// parseConstrDecls consumes constructor-declarations till EOF or function separator.
+// parseSeparator consumes a section separator and returns its name.
 //
-// constr-declarations ::= { declaration ; }
-// constr-declaration ::= combinator-decl | partial-app-decl | final-decldeclaration ;
+// ---functions--- | ---types---
 //
-func (p *Parser) parseConstrDecl() {
-	defer un(trace(p, "parseConstrDecl"))
-}
+func (p *Parser) parseSeparator() string {
+	defer un(trace(p, "parseSeparator"))
 
-// parseFuncDecls consumes function-declarations till EOF or type separator.
-//
-// fun-declarations ::= { declaration ; }
-// function-declaration ::= combinator-decl | partial-app-decl | final-decldeclaration ;
-//
-func (p *Parser) parseFuncDecl() {
-	defer un(trace(p, "parseFuncDecl"))
+	p.expect(ItemTripleMinus)
+
+	pos, name := p.pos, p.tok.Literal
+	p.expect(ItemLowerIdent)
+	if name != "functions" && name != "types" {
+		p.error(pos, fmt.Sprintf("expected functions or types separator, got %q", name))
+	}
+	p.expect(ItemTripleMinus)
+
+	return name
 }
 
 // parseDeclaration consumes a generic declaration.
 //
 // declaration ::= combinator-decl | partial-app-decl | final-decl
 //
-func (p *Parser) parseDecl() {
+func (p *Parser) parseDecl() Declaration {
 	defer un(trace(p, "parseDecl"))
 
-	tok := p.tok
-
-	switch tok.Token {
-	case ItemLowerIdent:
-		p.parseBuiltinCombinatorDecl()
+	switch p.tok.Token {
+	case ItemLowerIdent, ItemUnderscore:
+		switch {
+		case p.peek(1).Token == ItemQuestionMark:
+			return p.parseBuiltinCombinatorDecl()
+		case p.isPartialCombAppDecl():
+			return p.parsePartialCombAppDecl()
+		}
+		return p.parseCombinatorDecl()
 	case ItemUpperIdent:
-		p.parsePartialAppDecl()
+		return p.parsePartialAppDecl()
 	case ItemNew, ItemFinal, ItemEmpty:
-		p.parseFinalDecl()
-	default:
-		p.setErr(fmt.Errorf("unexpected token"))
-		return
+		return p.parseFinalDecl()
+	}
+
+	p.errorExpected(p.pos, "declaration")
+	p.next()
+	return nil
+}
+
+// isPartialCombAppDecl reports whether the declaration starting at the current
+// token lacks a result type, i.e. it ends before an '=' is seen.
+func (p *Parser) isPartialCombAppDecl() bool {
+	for i := 1; ; i++ {
+		switch p.peek(i).Token {
+		case ItemEquals, ItemEOF, ItemIllegal:
+			return false
+		case ItemSemicolon:
+			return true
+		}
 	}
 }
 
@@ -138,16 +175,15 @@ func (p *Parser) parseDecl() {
 //
 // builtin-combinator-decl ::= full-combinator-id ? = boxed-type-ident ;
 //
-func (p *Parser) parseBuiltinCombinatorDecl() BuiltinCombDecl {
+func (p *Parser) parseBuiltinCombinatorDecl() *BuiltinCombDecl {
 	defer un(trace(p, "parseBuiltinCombinatorDecl"))
 
 	id := p.parseFullCombinatorId()
-	p.expect(ItemQuestionMark)
+	quest := p.expect(ItemQuestionMark)
 	p.expect(ItemEquals)
 	result := p.parseBoxedTypeIdent()
-	p.expectSemi()
 
-	return BuiltinCombDecl{id, result}
+	return &BuiltinCombDecl{Id: id, Quest: quest, Result: result}
 }
 
 // parseCombinatorDecl consumes a combinator declaration.
@@ -156,40 +192,97 @@ func (p *Parser) parseBuiltinCombinatorDecl() BuiltinCombDecl {
 //
 // user#decafbad {id:int} name:string = User;
 //
-func (p *Parser) parseCombinatorDecl() {
+func (p *Parser) parseCombinatorDecl() *CombDecl {
 	defer un(trace(p, "parseCombinatorDecl"))
 
-	p.next()
+	p.vars = make(map[string]bool)
+	defer func() { p.vars = nil }()
+
+	decl := &CombDecl{Id: p.parseFullCombinatorId()}
+
+	for p.tok.Token == ItemOpenBrace && p.err == nil {
+		decl.OptArgs = append(decl.OptArgs, p.parseOptionalArgs())
+	}
+
+	for p.tok.Token != ItemEquals && p.tok.Token != ItemEOF && p.err == nil {
+		decl.Args = append(decl.Args, p.parseArgs())
+	}
+
+	decl.Equals = p.expect(ItemEquals)
+	decl.Result = p.parseResultType()
+
+	return decl
 }
 
-// parsePartialAppDecl consumes a partial-app-decl.
+// parsePartialAppDecl consumes a partial-type-app-decl.
 //
 // partial-app-decl ::= partial-type-app-decl | partial-comb-app-decl
 // partial-type-app-decl ::= boxed-type-ident subexpr { subexpr } ; | boxed-type-ident < expr { , expr } > ;
-// partial-comb-app-decl ::= combinator-id subexpr { subexpr } ;
 //
-func (p *Parser) parsePartialAppDecl() {
+func (p *Parser) parsePartialAppDecl() *PartialTypeAppDecl {
 	defer un(trace(p, "parsePartialAppDecl"))
 
-	p.next()
+	decl := &PartialTypeAppDecl{Name: p.parseBoxedTypeIdent()}
+	if p.tok.Token == ItemLeftAngle {
+		decl.Lang, decl.Args, decl.Rang = p.parseAngleArgs()
+		return decl
+	}
+
+	decl.Args = p.parseSubExprs()
+	if len(decl.Args) == 0 {
+		p.errorExpected(p.pos, "subexpr")
+	}
+
+	return decl
+}
+
+// parsePartialCombAppDecl consumes a partial-comb-app-decl.
+//
+// partial-comb-app-decl ::= combinator-id subexpr { subexpr } ;
+//
+func (p *Parser) parsePartialCombAppDecl() *PartialCombAppDecl {
+	defer un(trace(p, "parsePartialCombAppDecl"))
+
+	decl := &PartialCombAppDecl{Id: p.parseCombinatorId()}
+	decl.Args = p.parseSubExprs()
+	if len(decl.Args) == 0 {
+		p.errorExpected(p.pos, "subexpr")
+	}
+
+	return decl
 }
 
 // parseFinalDeclaration consumes a final declaration.
 //
 // final-decl ::= New boxed-type-ident ; | Final boxed-type-ident ; | Empty boxed-type-ident ;
 //
-func (p *Parser) parseFinalDecl() {
+func (p *Parser) parseFinalDecl() *FinalDecl {
 	defer un(trace(p, "parseFinalDecl"))
 
-	p.next()
+	decl := &FinalDecl{KindPos: p.pos, Kind: p.tok.Token}
+	p.expect(ItemNew, ItemFinal, ItemEmpty)
+	decl.Name = p.parseBoxedTypeIdent()
+
+	return decl
 }
 
 // parseResultType consumes a result-type.
 //
+// result-type ::= boxed-type-ident { subexpr }
 // result-type ::= boxed-type-ident < subexpr { , subexpr } >
 //
-func (p *Parser) parseResultType() {
+func (p *Parser) parseResultType() *ResultType {
 	defer un(trace(p, "parseResultType"))
+
+	result := &ResultType{Name: p.parseBoxedTypeIdent()}
+	if p.tok.Token == ItemLeftAngle {
+		result.Lang, result.Args, result.Rang = p.parseAngleArgs()
+		return result
+	}
+
+	result.Args = p.parseSubExprs()
+
+	return result
 }
 
 // parseOptionalArgs parses a combinator's optional arguments. All optional
@@ -197,8 +290,29 @@ func (p *Parser) parseResultType() {
 //
 // opt-args ::= '{' var-ident { var-ident } : [excl-mark] type-expr '}'
 //
-func (p *Parser) parseOptionalArgs() {
+func (p *Parser) parseOptionalArgs() *OptionalArg {
 	defer un(trace(p, "parseOptionalArgs"))
+
+	arg := &OptionalArg{Lbrace: p.expect(ItemOpenBrace)}
+
+	for p.tok.Token == ItemLowerIdent || p.tok.Token == ItemUpperIdent {
+		arg.Names = append(arg.Names, p.parseVarIdent())
+	}
+	if len(arg.Names) == 0 {
+		p.errorExpected(p.pos, "var-ident")
+	}
+
+	arg.Colon = p.expect(ItemColon)
+	if p.tok.Token == ItemExclMark {
+		arg.Excl = p.pos
+		p.next()
+	}
+	arg.Type = p.parseExpr()
+	arg.Rbrace = p.expect(ItemCloseBrace)
+
+	p.declare(arg.Names)
+
+	return arg
 }
 
 // parseArgs consumes required arguments of a combinator declaration.
@@ -218,62 +332,268 @@ func (p *Parser) parseOptionalArgs() {
 // args ::= '(' var-ident-opt { var-ident-opt } : [!] type-term ')'
 // args ::= [ '!' ] type-term
 //
-func (p *Parser) parseArgs() {
+func (p *Parser) parseArgs() *Arg {
 	defer un(trace(p, "parseArgs"))
+
+	arg := &Arg{}
+
+	switch {
+	case p.tok.Token == ItemOpenPar && p.isArgGroup():
+		arg.Lparen = p.pos
+		p.next()
+		for p.tok.Token != ItemColon && p.err == nil {
+			arg.Names = append(arg.Names, p.parseVarIdentOpt())
+		}
+		arg.Colon = p.expect(ItemColon)
+		if p.tok.Token == ItemExclMark {
+			arg.Excl = p.pos
+			p.next()
+		}
+		arg.Type = p.parseTerm()
+		arg.Rparen = p.expect(ItemClosePar)
+		p.declare(arg.Names)
+		return arg
+	case isVarIdentOpt(p.tok.Token) && p.peek(1).Token == ItemColon:
+		arg.Names = []*Ident{p.parseVarIdentOpt()}
+		arg.Colon = p.expect(ItemColon)
+		if p.tok.Token == ItemLowerIdent {
+			if next := p.peek(1).Token; next == ItemDot || next == ItemQuestionMark {
+				arg.Cond = p.parseConditionalArgDef()
+			}
+		}
+	}
+
+	switch p.tok.Token {
+	case ItemExclMark:
+		arg.Excl = p.pos
+		p.next()
+		arg.Type = p.parseTerm()
+	case ItemOpenBracket:
+		arg.Type = p.parseRepetition(nil, Pos{})
+	default:
+		typ := p.parseTerm()
+		if p.tok.Token == ItemAsterisk {
+			star := p.pos
+			p.next()
+			typ = p.parseRepetition(typ, star)
+		}
+		arg.Type = typ
+	}
+
+	p.declare(arg.Names)
+
+	return arg
+}
+
+// isArgGroup reports whether the '(' at the current token opens a group of
+// named arguments rather than a parenthesized type expression.
+func (p *Parser) isArgGroup() bool {
+	for i := 1; ; i++ {
+		switch tok := p.peek(i).Token; {
+		case tok == ItemColon:
+			return i > 1
+		case !isVarIdentOpt(tok):
+			return false
+		}
+	}
+}
+
+// parseRepetition consumes repeated arguments. mult is the already consumed
+// multiplicity, if any.
+//
+// [ multiplicity * ] '[' { args } ']'
+//
+func (p *Parser) parseRepetition(mult Expr, star Pos) *Repetition {
+	defer un(trace(p, "parseRepetition"))
+
+	rep := &Repetition{Mult: mult, Star: star, Lbrack: p.expect(ItemOpenBracket)}
+	for p.tok.Token != ItemCloseBracket && p.tok.Token != ItemEOF && p.err == nil {
+		rep.Args = append(rep.Args, p.parseArgs())
+	}
+	rep.Rbrack = p.expect(ItemCloseBracket)
+
+	return rep
+}
+
+// parseConditionalArgDef consumes a conditional-arg-def.
+//
+// conditional-arg-def ::= var-ident [ . nat-const ] ?
+//
+func (p *Parser) parseConditionalArgDef() *CondDef {
+	defer un(trace(p, "parseConditionalArgDef"))
+
+	cond := &CondDef{Field: p.parseVarIdent()}
+	if p.tok.Token == ItemDot {
+		cond.Dot = p.pos
+		p.next()
+		cond.Bit = &NatConst{ValuePos: p.pos, Value: p.tok.Literal}
+		p.expect(ItemNatConst)
+	}
+	cond.Quest = p.expect(ItemQuestionMark)
+
+	return cond
 }
 
-// parseExpr consumes multiple subexpr's.
+// parseExpr consumes multiple subexpr's. A single subexpr is returned as is,
+// multiple subexpr's are returned as a type application.
 //
 // expr ::= { subexpr }
 //
-func (p *Parser) parseExpr() {
+func (p *Parser) parseExpr() Expr {
 	defer un(trace(p, "parseExpr"))
+
+	list := p.parseSubExprs()
+	switch len(list) {
+	case 0:
+		p.errorExpected(p.pos, "expr")
+		return &TypeIdent{NamePos: p.pos}
+	case 1:
+		return list[0]
+	}
+
+	return &AppExpr{Fun: list[0], Args: list[1:]}
+}
+
+// parseSubExprs consumes subexpr's as long as the current token starts a term.
+func (p *Parser) parseSubExprs() []Expr {
+	var list []Expr
+	for isTermStart(p.tok.Token) && p.err == nil {
+		list = append(list, p.parseSubExpr())
+	}
+	return list
 }
 
 // parseSubExpr consumes a subexpr.
 //
 // subexpr ::= term | nat-const '+' subexpr | subexpr '+' nat-const
 //
-func (p *Parser) parseSubExpr() {
+func (p *Parser) parseSubExpr() Expr {
 	defer un(trace(p, "parseSubExpr"))
+
+	x := p.parseTerm()
+	for p.tok.Token == ItemPlus && p.err == nil {
+		plus := p.pos
+		p.next()
+		x = &SumExpr{X: x, Plus: plus, Y: p.parseTerm()}
+	}
+
+	return x
 }
 
 // parseTerm consumes a term.
 //
 // term ::= '(' expr ')' | type-ident | var-ident | nat-const | % term | type-ident '<' expr { ',' expr } '>'
 //
-func (p *Parser) parseTerm() {
+func (p *Parser) parseTerm() Expr {
 	defer un(trace(p, "parseTerm"))
+
+	switch p.tok.Token {
+	case ItemOpenPar:
+		x := &ParenExpr{Lparen: p.pos}
+		p.next()
+		x.X = p.parseExpr()
+		x.Rparen = p.expect(ItemClosePar)
+		return x
+	case ItemPercent:
+		x := &BareType{Percent: p.pos}
+		p.next()
+		x.X = p.parseTerm()
+		return x
+	case ItemNatConst:
+		x := &NatConst{ValuePos: p.pos, Value: p.tok.Literal}
+		p.next()
+		return x
+	case ItemLowerIdent, ItemUpperIdent:
+		if p.vars[p.tok.Literal] {
+			x := &Var{NamePos: p.pos, Name: p.tok.Literal}
+			p.next()
+			return x
+		}
+	}
+
+	var x Expr = p.parseTypeIdent()
+	if p.tok.Token == ItemLeftAngle {
+		app := &AppExpr{Fun: x}
+		app.Lang, app.Args, app.Rang = p.parseAngleArgs()
+		x = app
+	}
+
+	return x
+}
+
+// parseAngleArgs consumes a comma separated list of expressions enclosed in
+// angle brackets.
+//
+// '<' expr { ',' expr } '>'
+//
+func (p *Parser) parseAngleArgs() (lang Pos, args []Expr, rang Pos) {
+	defer un(trace(p, "parseAngleArgs"))
+
+	lang = p.expect(ItemLeftAngle)
+	args = append(args, p.parseExpr())
+	for p.tok.Token == ItemComma && p.err == nil {
+		p.next()
+		args = append(args, p.parseExpr())
+	}
+	rang = p.expect(ItemRightAngle)
+
+	return lang, args, rang
 }
 
 // parseTypeIdent consumes a type-ident.
 //
 // type-ident ::= boxed-type-ident | lc-ident-ns | #
 //
-func (p *Parser) parseTypeIdent() TypeIdent {
+func (p *Parser) parseTypeIdent() *TypeIdent {
 	defer un(trace(p, "parseTypeIdent"))
 
-	tok := p.tok
+	tok, pos := p.tok, p.pos
 	if tok.Token == ItemLowerIdent && tok.HasName() {
-		p.error("got lc-ident-full, expected lc-ident-ns")
+		p.error(pos, "got lc-ident-full, expected lc-ident-ns")
 	}
 
 	p.expect(ItemUpperIdent, ItemLowerIdent, ItemHash)
 
-	return TypeIdent{tok.Literal}
+	return &TypeIdent{NamePos: pos, Name: tok.Literal}
 }
 
 // parseBoxedType consumes a boxed-type-ident.
 //
 // boxed-type-ident ::= uc-ident-ns
 //
-func (p *Parser) parseBoxedTypeIdent() BoxedTypeIdent {
+func (p *Parser) parseBoxedTypeIdent() *BoxedTypeIdent {
 	defer un(trace(p, "parseBoxedTypeIdent"))
 
 	name := p.tok.Literal
-	p.expect(ItemUpperIdent)
+	pos := p.expect(ItemUpperIdent)
+
+	return &BoxedTypeIdent{NamePos: pos, Name: name}
+}
+
+// parseVarIdent consumes a var-ident.
+//
+// var-ident ::= lc-ident | uc-ident
+//
+func (p *Parser) parseVarIdent() *Ident {
+	defer un(trace(p, "parseVarIdent"))
+
+	tok := p.tok
+	pos := p.expect(ItemLowerIdent, ItemUpperIdent)
 
-	return BoxedTypeIdent{name}
+	return &Ident{NamePos: pos, Name: tok}
+}
+
+// parseVarIdentOpt consumes a var-ident-opt.
+//
+// var-ident-opt ::= var-ident | _
+//
+func (p *Parser) parseVarIdentOpt() *Ident {
+	defer un(trace(p, "parseVarIdentOpt"))
+
+	tok := p.tok
+	pos := p.expect(ItemLowerIdent, ItemUpperIdent, ItemUnderscore)
+
+	return &Ident{NamePos: pos, Name: tok}
 }
 
 // parseCombinatorId consumes a combinator-id
@@ -281,14 +601,14 @@ func (p *Parser) parseBoxedTypeIdent() BoxedTypeIdent {
 // combinator-id ::= lc-ident-ns | _
 // e.g.: user | group.user | _
 //
-func (p *Parser) parseCombinatorId() CombinatorId {
+func (p *Parser) parseCombinatorId() *CombinatorId {
 	// FIXME: handle lc-ident-full case.
 	defer un(trace(p, "parseCombinatorId"))
 
 	tok := p.tok
-	p.expect(ItemLowerIdent, ItemUnderscore)
+	pos := p.expect(ItemLowerIdent, ItemUnderscore)
 
-	return CombinatorId{Ident{tok}}
+	return &CombinatorId{&Ident{NamePos: pos, Name: tok}}
 }
 
 // parseFullCombinatorId consumes a full-combinator-id
@@ -296,49 +616,111 @@ func (p *Parser) parseCombinatorId() CombinatorId {
 // full-combinator-id ::= lc-ident-full | _
 // e.g.: user#decafbad | user | group.user | group.user#decafbad
 //
-func (p *Parser) parseFullCombinatorId() FullCombinatorId {
+func (p *Parser) parseFullCombinatorId() *FullCombinatorId {
 	defer un(trace(p, "parseFullCombinatorId"))
 
 	tok := p.tok
-	p.expect(ItemLowerIdent, ItemUnderscore)
+	pos := p.expect(ItemLowerIdent, ItemUnderscore)
+
+	return &FullCombinatorId{&Ident{NamePos: pos, Name: tok}}
+}
+
+// declare records the given field names so that later references to them are
+// parsed as variables.
+func (p *Parser) declare(names []*Ident) {
+	if p.vars == nil {
+		return
+	}
+	for _, name := range names {
+		if name.Name.Token != ItemUnderscore {
+			p.vars[name.Text()] = true
+		}
+	}
+}
 
-	return FullCombinatorId{Ident{tok}}
+// isVarIdentOpt reports whether the item can be a var-ident-opt.
+func isVarIdentOpt(item Item) bool {
+	return item == ItemLowerIdent || item == ItemUpperIdent || item == ItemUnderscore
 }
 
-// next advances to the next non-whitespace token.
+// isTermStart reports whether the item can start a term.
+func isTermStart(item Item) bool {
+	switch item {
+	case ItemOpenPar, ItemPercent, ItemNatConst, ItemLowerIdent, ItemUpperIdent, ItemHash:
+		return true
+	}
+	return false
+}
+
+// next advances to the next non-whitespace, non-comment token.
 func (p *Parser) next() {
+	if len(p.buf) > 0 {
+		p.tok, p.pos = p.buf[0].tok, p.buf[0].pos
+		p.buf = p.buf[1:]
+		return
+	}
+
+	p.tok, p.pos = p.scan()
+}
+
+// peek returns the nth token after the current token without advancing the
+// parser.
+func (p *Parser) peek(n int) Token {
+	for len(p.buf) < n {
+		tok, pos := p.scan()
+		p.buf = append(p.buf, scannedToken{tok, pos})
+	}
+
+	return p.buf[n-1].tok
+}
+
+// scan reads the next non-whitespace, non-comment token from the scanner.
+func (p *Parser) scan() (Token, Pos) {
 	for {
-		p.tok = p.s.Scan()
+		tok := p.s.Scan()
+		if err := p.s.Err(); err != nil {
+			p.setErr(err)
+		}
 
-		// skip whitespace
-		if p.tok.Token != ItemWhitespace {
-			break
+		switch tok.Token {
+		case ItemWhitespace:
+			continue
+		case ItemComment:
+			p.comments = append(p.comments, &Comment{Slash: p.s.Pos(), Text: tok.Literal})
+			continue
+		case ItemIllegal:
+			p.error(p.s.Pos(), fmt.Sprintf("illegal token %q", tok.Literal))
 		}
+
+		return tok, p.s.Pos()
 	}
 }
 
 // expect checks if the current token is in the given items list, then advances
-// to the next non-whitespace token.
-func (p *Parser) expect(items ...Item) {
-	var errc int
+// to the next non-whitespace token. It returns the position of the consumed
+// token.
+func (p *Parser) expect(items ...Item) Pos {
+	pos := p.pos
 
+	var found bool
 	for _, item := range items {
 		if p.tok.Token == item {
-			errc = 0
+			found = true
 			break
 		}
-		errc += 1
 	}
 
-	if errc != 0 {
-		p.errorExpected(fmt.Sprintf("got: %s, want: '%v'", p.tok, items[:errc]))
+	if !found {
+		p.errorExpected(pos, fmt.Sprintf("%v, got %v", items, p.tok.Token))
 	}
 	p.next()
+
+	return pos
 }
 
 func (p *Parser) expectSemi() {
 	if p.tok.Token != ItemSemicolon {
-		p.setErr(fmt.Errorf("expected semicolon"))
+		p.errorExpected(p.pos, fmt.Sprintf("semicolon, got %v", p.tok.Token))
 	}
 
 	p.next()
@@ -372,6 +754,5 @@ func un(p *Parser) {
 	p.printTrace(")")
 }
 
-// func (p *Parser) error(msg string)         { p.errors = append(p.errors, errors.New(msg)) }
-func (p *Parser) error(msg string)         { log.Fatal(msg) }
-func (p *Parser) errorExpected(msg string) { p.error("expected " + msg) }
+func (p *Parser) error(pos Pos, msg string)         { p.setErr(&Error{Pos: pos, Msg: msg}) }
+func (p *Parser) errorExpected(pos Pos, msg string) { p.error(pos, "expected "+msg) }
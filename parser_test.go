@@ -2,6 +2,7 @@ package tl
 
 import (
 	"bytes"
+	"os"
 	"reflect"
 	"testing"
 )
@@ -9,13 +10,13 @@ import (
 func TestParser_parseBoxedTypeIdent(t *testing.T) {
 	var tests = []struct {
 		s string
-		b BoxedTypeIdent
+		b *BoxedTypeIdent
 	}{
-		{`Int`, BoxedTypeIdent{"Int"}},
-		{`Long`, BoxedTypeIdent{"Long"}},
-		{`Int128`, BoxedTypeIdent{"Int128"}},
-		{`String`, BoxedTypeIdent{"String"}},
-		{`Double`, BoxedTypeIdent{"Double"}},
+		{`Int`, &BoxedTypeIdent{pos(0), "Int"}},
+		{`Long`, &BoxedTypeIdent{pos(0), "Long"}},
+		{`Int128`, &BoxedTypeIdent{pos(0), "Int128"}},
+		{`String`, &BoxedTypeIdent{pos(0), "String"}},
+		{`Double`, &BoxedTypeIdent{pos(0), "Double"}},
 	}
 
 	for i, tt := range tests {
@@ -31,7 +32,7 @@ func TestParser_parseBoxedTypeIdent(t *testing.T) {
 		}
 
 		if !reflect.DeepEqual(b, tt.b) {
-			t.Errorf("<%d> bad type for %q: got %#v, expected %#v", i, tt.b, b, tt.b)
+			t.Errorf("<%d> bad type for %q: got %#v, expected %#v", i, tt.s, b, tt.b)
 		}
 	}
 }
@@ -39,14 +40,14 @@ func TestParser_parseBoxedTypeIdent(t *testing.T) {
 func TestParser_parseTypeIdent(t *testing.T) {
 	var tests = []struct {
 		s string
-		b TypeIdent
+		b *TypeIdent
 	}{
-		{s: `int`, b: TypeIdent{"int"}},
-		{s: `user`, b: TypeIdent{"user"}},
-		{s: `users.user`, b: TypeIdent{"users.user"}},
-		{s: `User`, b: TypeIdent{"User"}},
-		{s: `users.User`, b: TypeIdent{"users.User"}},
-		{s: `#`, b: TypeIdent{"#"}},
+		{s: `int`, b: &TypeIdent{pos(0), "int"}},
+		{s: `user`, b: &TypeIdent{pos(0), "user"}},
+		{s: `users.user`, b: &TypeIdent{pos(0), "users.user"}},
+		{s: `User`, b: &TypeIdent{pos(0), "User"}},
+		{s: `users.User`, b: &TypeIdent{pos(0), "users.User"}},
+		{s: `#`, b: &TypeIdent{pos(0), "#"}},
 	}
 
 	for i, tt := range tests {
@@ -62,7 +63,7 @@ func TestParser_parseTypeIdent(t *testing.T) {
 		}
 
 		if !reflect.DeepEqual(b, tt.b) {
-			t.Errorf("<%d> bad type for %q: got %#v, expected %#v", i, tt.b, b, tt.b)
+			t.Errorf("<%d> bad type for %q: got %#v, expected %#v", i, tt.s, b, tt.b)
 		}
 	}
 }
@@ -70,14 +71,14 @@ func TestParser_parseTypeIdent(t *testing.T) {
 func TestParser_parseCombinatorId(t *testing.T) {
 	var tests = []struct {
 		s  string
-		id CombinatorId
+		id *CombinatorId
 	}{
-		{s: `int`, id: CombinatorId{NewIdent("int")}},
-		{s: `user`, id: CombinatorId{NewIdent("user")}},
-		{s: `users.user`, id: CombinatorId{NewIdent("users.user")}},
-		{s: `users.user#decafbad`, id: CombinatorId{NewIdent("users.user#decafbad")}},
-		{s: `user#decafbad`, id: CombinatorId{NewIdent("user#decafbad")}},
-		{s: `_`, id: CombinatorId{NewIdent("_")}},
+		{s: `int`, id: &CombinatorId{ident(0, "int")}},
+		{s: `user`, id: &CombinatorId{ident(0, "user")}},
+		{s: `users.user`, id: &CombinatorId{ident(0, "users.user")}},
+		{s: `users.user#decafbad`, id: &CombinatorId{ident(0, "users.user#decafbad")}},
+		{s: `user#decafbad`, id: &CombinatorId{ident(0, "user#decafbad")}},
+		{s: `_`, id: &CombinatorId{ident(0, "_")}},
 	}
 
 	for i, tt := range tests {
@@ -93,7 +94,7 @@ func TestParser_parseCombinatorId(t *testing.T) {
 		}
 
 		if !reflect.DeepEqual(id, tt.id) {
-			t.Errorf("<%d> bad type for %q: got %#v, expected %#v", i, tt.id, id, tt.id)
+			t.Errorf("<%d> bad type for %q: got %#v, expected %#v", i, tt.s, id, tt.id)
 		}
 	}
 }
@@ -101,14 +102,14 @@ func TestParser_parseCombinatorId(t *testing.T) {
 func TestParser_parseFullCombinatorId(t *testing.T) {
 	var tests = []struct {
 		s  string
-		id FullCombinatorId
+		id *FullCombinatorId
 	}{
-		{s: `int`, id: FullCombinatorId{NewIdent("int")}},
-		{s: `user`, id: FullCombinatorId{NewIdent("user")}},
-		{s: `users.user`, id: FullCombinatorId{NewIdent("users.user")}},
-		{s: `users.user#decafbad`, id: FullCombinatorId{NewIdent("users.user#decafbad")}},
-		{s: `user#decafbad`, id: FullCombinatorId{NewIdent("user#decafbad")}},
-		{s: `_`, id: FullCombinatorId{NewIdent("_")}},
+		{s: `int`, id: &FullCombinatorId{ident(0, "int")}},
+		{s: `user`, id: &FullCombinatorId{ident(0, "user")}},
+		{s: `users.user`, id: &FullCombinatorId{ident(0, "users.user")}},
+		{s: `users.user#decafbad`, id: &FullCombinatorId{ident(0, "users.user#decafbad")}},
+		{s: `user#decafbad`, id: &FullCombinatorId{ident(0, "user#decafbad")}},
+		{s: `_`, id: &FullCombinatorId{ident(0, "_")}},
 	}
 
 	for i, tt := range tests {
@@ -124,7 +125,7 @@ func TestParser_parseFullCombinatorId(t *testing.T) {
 		}
 
 		if !reflect.DeepEqual(id, tt.id) {
-			t.Errorf("<%d> bad type for %q: got %#v, expected %#v", i, tt.id, id, tt.id)
+			t.Errorf("<%d> bad type for %q: got %#v, expected %#v", i, tt.s, id, tt.id)
 		}
 	}
 }
@@ -132,12 +133,12 @@ func TestParser_parseFullCombinatorId(t *testing.T) {
 func TestParser_parseBuiltinCombinatorDecl(t *testing.T) {
 	var tests = []struct {
 		s string
-		b BuiltinCombDecl
+		b *BuiltinCombDecl
 	}{
-		{`int ?= Int;`, BuiltinCombDecl{FullCombinatorId{NewIdent("int")}, BoxedTypeIdent{"Int"}}},
-		{`long ?= Long;`, BuiltinCombDecl{FullCombinatorId{NewIdent("long")}, BoxedTypeIdent{"Long"}}},
-		{`double ?= Double;`, BuiltinCombDecl{FullCombinatorId{NewIdent("double")}, BoxedTypeIdent{"Double"}}},
-		{`string ?= String;`, BuiltinCombDecl{FullCombinatorId{NewIdent("string")}, BoxedTypeIdent{"String"}}},
+		{`int ?= Int;`, &BuiltinCombDecl{&FullCombinatorId{ident(0, "int")}, pos(4), &BoxedTypeIdent{pos(7), "Int"}}},
+		{`long ?= Long;`, &BuiltinCombDecl{&FullCombinatorId{ident(0, "long")}, pos(5), &BoxedTypeIdent{pos(8), "Long"}}},
+		{`double ?= Double;`, &BuiltinCombDecl{&FullCombinatorId{ident(0, "double")}, pos(7), &BoxedTypeIdent{pos(10), "Double"}}},
+		{`string ?= String;`, &BuiltinCombDecl{&FullCombinatorId{ident(0, "string")}, pos(7), &BoxedTypeIdent{pos(10), "String"}}},
 	}
 
 	for i, tt := range tests {
@@ -153,7 +154,215 @@ func TestParser_parseBuiltinCombinatorDecl(t *testing.T) {
 		}
 
 		if !reflect.DeepEqual(b, tt.b) {
-			t.Errorf("<%d> bad type for %q: got %#v, expected %#v", i, tt.b, b, tt.b)
+			t.Errorf("<%d> bad type for %q: got %#v, expected %#v", i, tt.s, b, tt.b)
 		}
 	}
 }
+
+func TestParser_parseCombinatorDecl(t *testing.T) {
+	var tests = []struct {
+		s    string
+		decl *CombDecl
+	}{
+		{
+			`vector {t:Type} # [t] = Vector t;`,
+			&CombDecl{
+				Id: &FullCombinatorId{ident(0, "vector")},
+				OptArgs: []*OptionalArg{
+					{Lbrace: pos(7), Names: []*Ident{ident(8, "t")}, Colon: pos(9), Type: &TypeIdent{pos(10), "Type"}, Rbrace: pos(14)},
+				},
+				Args: []*Arg{
+					{Type: &TypeIdent{pos(16), "#"}},
+					{Type: &Repetition{Lbrack: pos(18), Args: []*Arg{{Type: &Var{pos(19), "t"}}}, Rbrack: pos(20)}},
+				},
+				Equals: pos(22),
+				Result: &ResultType{Name: &BoxedTypeIdent{pos(24), "Vector"}, Args: []Expr{&Var{pos(31), "t"}}},
+			},
+		},
+		{
+			`vectorTotal {t:Type} total_count:int vector:%(Vector t) = VectorTotal t;`,
+			&CombDecl{
+				Id: &FullCombinatorId{ident(0, "vectorTotal")},
+				OptArgs: []*OptionalArg{
+					{Lbrace: pos(12), Names: []*Ident{ident(13, "t")}, Colon: pos(14), Type: &TypeIdent{pos(15), "Type"}, Rbrace: pos(19)},
+				},
+				Args: []*Arg{
+					{Names: []*Ident{ident(21, "total_count")}, Colon: pos(32), Type: &TypeIdent{pos(33), "int"}},
+					{Names: []*Ident{ident(37, "vector")}, Colon: pos(43), Type: &BareType{
+						Percent: pos(44),
+						X: &ParenExpr{
+							Lparen: pos(45),
+							X:      &AppExpr{Fun: &TypeIdent{pos(46), "Vector"}, Args: []Expr{&Var{pos(53), "t"}}},
+							Rparen: pos(54),
+						},
+					}},
+				},
+				Equals: pos(56),
+				Result: &ResultType{Name: &BoxedTypeIdent{pos(58), "VectorTotal"}, Args: []Expr{&Var{pos(70), "t"}}},
+			},
+		},
+		{
+			`invokeWithLayer#da9b0d0d {X:Type} layer:int query:!X = X;`,
+			&CombDecl{
+				Id: &FullCombinatorId{ident(0, "invokeWithLayer#da9b0d0d")},
+				OptArgs: []*OptionalArg{
+					{Lbrace: pos(25), Names: []*Ident{ident(26, "X")}, Colon: pos(27), Type: &TypeIdent{pos(28), "Type"}, Rbrace: pos(32)},
+				},
+				Args: []*Arg{
+					{Names: []*Ident{ident(34, "layer")}, Colon: pos(39), Type: &TypeIdent{pos(40), "int"}},
+					{Names: []*Ident{ident(44, "query")}, Colon: pos(49), Excl: pos(50), Type: &Var{pos(51), "X"}},
+				},
+				Equals: pos(53),
+				Result: &ResultType{Name: &BoxedTypeIdent{pos(55), "X"}},
+			},
+		},
+		{
+			`getUsers#2d84d5f5 (Vector int) = Vector<User>;`,
+			&CombDecl{
+				Id: &FullCombinatorId{ident(0, "getUsers#2d84d5f5")},
+				Args: []*Arg{
+					{Type: &ParenExpr{
+						Lparen: pos(18),
+						X:      &AppExpr{Fun: &TypeIdent{pos(19), "Vector"}, Args: []Expr{&TypeIdent{pos(26), "int"}}},
+						Rparen: pos(29),
+					}},
+				},
+				Equals: pos(31),
+				Result: &ResultType{Name: &BoxedTypeIdent{pos(33), "Vector"}, Lang: pos(39), Args: []Expr{&TypeIdent{pos(40), "User"}}, Rang: pos(44)},
+			},
+		},
+		{
+			`msg flags:# (a b:flags.0?true) n:# 2*[x:int] = Msg n+1;`,
+			nil, // arg groups may not carry conditions
+		},
+		{
+			`msg flags:# x:flags.10?string n:# n*[id:long] = Msg;`,
+			&CombDecl{
+				Id: &FullCombinatorId{ident(0, "msg")},
+				Args: []*Arg{
+					{Names: []*Ident{ident(4, "flags")}, Colon: pos(9), Type: &TypeIdent{pos(10), "#"}},
+					{
+						Names: []*Ident{ident(12, "x")},
+						Colon: pos(13),
+						Cond:  &CondDef{Field: ident(14, "flags"), Dot: pos(19), Bit: &NatConst{pos(20), "10"}, Quest: pos(22)},
+						Type:  &TypeIdent{pos(23), "string"},
+					},
+					{Names: []*Ident{ident(30, "n")}, Colon: pos(31), Type: &TypeIdent{pos(32), "#"}},
+					{Type: &Repetition{
+						Mult:   &Var{pos(34), "n"},
+						Star:   pos(35),
+						Lbrack: pos(36),
+						Args:   []*Arg{{Names: []*Ident{ident(37, "id")}, Colon: pos(39), Type: &TypeIdent{pos(40), "long"}}},
+						Rbrack: pos(44),
+					}},
+				},
+				Equals: pos(46),
+				Result: &ResultType{Name: &BoxedTypeIdent{pos(48), "Msg"}},
+			},
+		},
+	}
+
+	for i, tt := range tests {
+		parser := NewParser(bytes.NewBufferString(tt.s))
+
+		// initial parse
+		parser.next()
+
+		decl := parser.parseCombinatorDecl()
+
+		if tt.decl == nil {
+			if parser.Err() == nil {
+				t.Errorf("<%d> expected error for %q", i, tt.s)
+			}
+			continue
+		}
+
+		if parser.Err() != nil {
+			t.Errorf("got error: %v", parser.Err())
+		}
+
+		if !reflect.DeepEqual(decl, tt.decl) {
+			t.Errorf("<%d> bad declaration for %q: got %#v, expected %#v", i, tt.s, decl, tt.decl)
+		}
+
+		if end := decl.End(); end.Offset != len(tt.s)-1 {
+			t.Errorf("<%d> bad end for %q: got %v, expected offset %d", i, tt.s, end, len(tt.s)-1)
+		}
+	}
+}
+
+func TestParser_Parse(t *testing.T) {
+	var tests = []struct {
+		file string
+
+		constructors int
+		functions    int
+	}{
+		{"common.tl", 17, 0},
+		{"schema.tl", 314, 113},
+	}
+
+	for _, tt := range tests {
+		f, err := os.Open(tt.file)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		parser := NewParser(f)
+		program := parser.Parse()
+		f.Close()
+
+		if parser.Err() != nil {
+			t.Errorf("%v: got error: %v", tt.file, parser.Err())
+			continue
+		}
+
+		if len(program.Constructors) != tt.constructors {
+			t.Errorf("%v: got %d constructors, expected %d", tt.file, len(program.Constructors), tt.constructors)
+		}
+		if len(program.Functions) != tt.functions {
+			t.Errorf("%v: got %d functions, expected %d", tt.file, len(program.Functions), tt.functions)
+		}
+	}
+}
+
+func TestParser_ParseErrors(t *testing.T) {
+	var tests = []struct {
+		s   string
+		pos Pos
+	}{
+		{`user id:int = ;`, pos(14)},
+		{`user id:int = User`, pos(18)},
+		{`user {t:Type = User;`, pos(13)},
+		{"int ? = Int;\n---function---", Pos{Offset: 16, Line: 2, Column: 4}},
+		{`Final;`, pos(5)},
+		{`user id:@ = User;`, pos(8)},
+	}
+
+	for i, tt := range tests {
+		parser := NewParser(bytes.NewBufferString(tt.s))
+		parser.Parse()
+
+		err, ok := parser.Err().(*Error)
+		if !ok {
+			t.Errorf("<%d> expected syntax error for %q, got %v", i, tt.s, parser.Err())
+			continue
+		}
+
+		if err.Pos != tt.pos {
+			t.Errorf("<%d> bad error position for %q: got %v, expected %v (%v)", i, tt.s, err.Pos, tt.pos, err)
+		}
+	}
+}
+
+// pos returns the position of the given offset on the first line.
+func pos(offset int) Pos {
+	return Pos{Offset: offset, Line: 1, Column: offset + 1}
+}
+
+// ident returns an identifier at the given offset on the first line.
+func ident(offset int, name string) *Ident {
+	id := NewIdent(name)
+	id.NamePos = pos(offset)
+	return id
+}
@@ -0,0 +1,450 @@
+// Package printer implements printing of TL AST nodes in canonical form.
+//
+// Declarations are printed one per line with a single space between
+// contiguous lexemes, no spaces around ':' and a single space around '='.
+// Comments are preserved and blank lines between declarations are collapsed
+// into a single blank line. Section separators are surrounded by blank lines.
+package printer
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/igungor/tl"
+)
+
+// Style determines how type applications are printed.
+type Style int
+
+const (
+	// KeepStyle prints type applications the way they are written.
+	KeepStyle Style = iota
+	// AngleStyle prints type applications as Vector<T>.
+	AngleStyle
+	// SpaceStyle prints type applications as Vector T.
+	SpaceStyle
+)
+
+// A Config node controls the output of Fprint.
+type Config struct {
+	Style Style
+}
+
+// Fprint "pretty-prints" an AST node to output using the default config.
+func Fprint(output io.Writer, node tl.Node) error {
+	return (&Config{}).Fprint(output, node)
+}
+
+// Fprint "pretty-prints" an AST node to output for a given configuration cfg.
+// A *tl.Program is printed as a TL source file, declarations are printed with
+// their terminating semicolon and all other nodes are printed as is.
+func (cfg *Config) Fprint(output io.Writer, node tl.Node) error {
+	p := &printer{Config: *cfg}
+
+	switch n := node.(type) {
+	case *tl.Program:
+		p.program(n)
+	case tl.Declaration:
+		p.decl(n)
+		p.print(";")
+	case tl.Expr:
+		p.expr(n, exprCtx)
+	default:
+		p.node(n)
+	}
+
+	_, err := output.Write(p.buf.Bytes())
+	return err
+}
+
+// The printing context of an expression determines whether it has to be
+// enclosed in parentheses.
+type context int
+
+const (
+	exprCtx    context = iota // expr ::= { subexpr }
+	subExprCtx                // subexpr, an element of an expr
+	termCtx                   // term, e.g. the type of an argument
+)
+
+type printer struct {
+	Config
+
+	buf bytes.Buffer
+
+	comments []*tl.Comment // comments not printed yet
+	lastLine int           // source line of the last printed item; or 0
+	blank    bool          // whether the next item is preceded by a blank line
+}
+
+func (p *printer) print(args ...string) {
+	for _, s := range args {
+		p.buf.WriteString(s)
+	}
+}
+
+// program prints all sections of the program along with its comments. The
+// sections of a parsed program are printed in source order, others as a
+// section of each kind.
+func (p *printer) program(prog *tl.Program) {
+	p.comments = prog.Comments
+
+	if len(prog.Separators) > 0 {
+		p.sections(prog)
+	} else {
+		p.declList(prog.Constructors)
+		if len(prog.Functions) > 0 || prog.FunctionsSep.IsValid() {
+			p.separator("functions", prog.FunctionsSep)
+			p.declList(prog.Functions)
+		}
+		if len(prog.Types) > 0 || prog.TypesSep.IsValid() {
+			p.separator("types", prog.TypesSep)
+			p.declList(prog.Types)
+		}
+	}
+
+	p.flush(tl.Pos{})
+	if p.buf.Len() > 0 {
+		p.print("\n")
+	}
+}
+
+// sections prints the declarations and the separators of prog in source
+// order, e.g. a ---types--- section between two ---functions--- sections.
+func (p *printer) sections(prog *tl.Program) {
+	var decls []tl.Declaration
+	for _, list := range [][]tl.Declaration{prog.Constructors, prog.Functions, prog.Types} {
+		decls = append(decls, list...)
+	}
+	sort.SliceStable(decls, func(i, j int) bool {
+		return decls[i].Pos().Offset < decls[j].Pos().Offset
+	})
+
+	for _, sep := range prog.Separators {
+		i := sort.Search(len(decls), func(i int) bool {
+			return decls[i].Pos().Offset > sep.Start.Offset
+		})
+		p.declList(decls[:i])
+		decls = decls[i:]
+		p.separator(sep.Name, sep.Start)
+	}
+	p.declList(decls)
+}
+
+func (p *printer) declList(list []tl.Declaration) {
+	for _, decl := range list {
+		p.linebreak(decl.Pos())
+		p.decl(decl)
+		p.print(";")
+		p.lastLine = decl.End().Line
+	}
+}
+
+// separator prints a section separator surrounded by blank lines.
+func (p *printer) separator(name string, pos tl.Pos) {
+	p.flush(pos)
+	if p.buf.Len() > 0 {
+		p.print("\n\n")
+	}
+	p.print("---", name, "---")
+	p.lastLine = pos.Line
+	p.blank = true
+}
+
+// linebreak prints the comments preceding an item at pos followed by the
+// line break, and a blank line if the source has one, before the item.
+func (p *printer) linebreak(pos tl.Pos) {
+	p.flush(pos)
+	p.newline(pos)
+}
+
+func (p *printer) newline(pos tl.Pos) {
+	if p.buf.Len() == 0 {
+		p.blank = false
+		return
+	}
+
+	p.print("\n")
+	if p.blank || (pos.IsValid() && p.lastLine > 0 && pos.Line-p.lastLine > 1) {
+		p.print("\n")
+	}
+	p.blank = false
+}
+
+// flush prints all pending comments before pos. An invalid pos flushes all
+// remaining comments. A comment on the same line as the last printed item is
+// printed at the end of that line.
+func (p *printer) flush(pos tl.Pos) {
+	for len(p.comments) > 0 {
+		c := p.comments[0]
+		if pos.IsValid() && c.Pos().Offset >= pos.Offset {
+			return
+		}
+		p.comments = p.comments[1:]
+
+		if p.buf.Len() > 0 && c.Pos().Line == p.lastLine {
+			p.print(" ", c.Text)
+			continue
+		}
+
+		p.newline(c.Pos())
+		p.print(c.Text)
+		p.lastLine = c.Pos().Line
+	}
+}
+
+func (p *printer) decl(decl tl.Declaration) {
+	switch d := decl.(type) {
+	case *tl.CombDecl:
+		p.print(d.Id.Id.Text())
+		for _, arg := range d.OptArgs {
+			p.print(" ")
+			p.optArg(arg)
+		}
+		for _, arg := range d.Args {
+			p.print(" ")
+			p.arg(arg)
+		}
+		p.print(" = ")
+		p.result(d.Result.Name.Name, d.Result.Lang.IsValid(), d.Result.Args)
+
+	case *tl.BuiltinCombDecl:
+		p.print(d.Id.Id.Text(), " ? = ", d.Result.Name)
+
+	case *tl.PartialTypeAppDecl:
+		p.result(d.Name.Name, d.Lang.IsValid(), d.Args)
+
+	case *tl.PartialCombAppDecl:
+		p.print(d.Id.Id.Text())
+		for _, x := range d.Args {
+			p.print(" ")
+			p.expr(x, subExprCtx)
+		}
+
+	case *tl.FinalDecl:
+		p.print(finalKeywords[d.Kind], " ", d.Name.Name)
+
+	default:
+		panic(fmt.Sprintf("printer: unexpected declaration %T", decl))
+	}
+}
+
+var finalKeywords = map[tl.Item]string{
+	tl.ItemNew:   "New",
+	tl.ItemFinal: "Final",
+	tl.ItemEmpty: "Empty",
+}
+
+// result prints a result type or a partial type application, e.g. Vector t
+// or Vector<t>.
+func (p *printer) result(name string, angle bool, args []tl.Expr) {
+	p.print(name)
+	if len(args) == 0 {
+		return
+	}
+
+	switch p.Style {
+	case AngleStyle:
+		angle = true
+	case SpaceStyle:
+		angle = false
+	}
+
+	if angle {
+		p.print("<")
+		p.exprList(args, ",", exprCtx)
+		p.print(">")
+		return
+	}
+
+	p.print(" ")
+	p.exprList(args, " ", subExprCtx)
+}
+
+func (p *printer) optArg(arg *tl.OptionalArg) {
+	p.print("{")
+	p.identList(arg.Names)
+	p.print(":")
+	if arg.Excl.IsValid() {
+		p.print("!")
+	}
+	p.expr(arg.Type, exprCtx)
+	p.print("}")
+}
+
+func (p *printer) arg(arg *tl.Arg) {
+	if arg.Lparen.IsValid() {
+		p.print("(")
+		p.identList(arg.Names)
+		p.print(":")
+		if arg.Excl.IsValid() {
+			p.print("!")
+		}
+		p.expr(arg.Type, termCtx)
+		p.print(")")
+		return
+	}
+
+	if len(arg.Names) > 0 {
+		p.identList(arg.Names)
+		p.print(":")
+	}
+	if arg.Cond != nil {
+		p.cond(arg.Cond)
+	}
+	if arg.Excl.IsValid() {
+		p.print("!")
+	}
+	p.expr(arg.Type, termCtx)
+}
+
+func (p *printer) cond(c *tl.CondDef) {
+	p.print(c.Field.Text())
+	if c.Bit != nil {
+		p.print(".", c.Bit.Value)
+	}
+	p.print("?")
+}
+
+func (p *printer) identList(list []*tl.Ident) {
+	for i, id := range list {
+		if i > 0 {
+			p.print(" ")
+		}
+		p.print(id.Text())
+	}
+}
+
+func (p *printer) exprList(list []tl.Expr, sep string, ctx context) {
+	for i, x := range list {
+		if i > 0 {
+			p.print(sep)
+		}
+		p.expr(x, ctx)
+	}
+}
+
+func (p *printer) expr(x tl.Expr, ctx context) {
+	switch x := x.(type) {
+	case *tl.TypeIdent:
+		p.print(x.Name)
+
+	case *tl.Var:
+		p.print(x.Name)
+
+	case *tl.NatConst:
+		p.print(x.Value)
+
+	case *tl.BareType:
+		p.print("%")
+		p.expr(x.X, termCtx)
+
+	case *tl.ParenExpr:
+		if app, ok := x.X.(*tl.AppExpr); ok && p.angle(app) {
+			p.expr(app, termCtx)
+			return
+		}
+		p.print("(")
+		p.expr(x.X, exprCtx)
+		p.print(")")
+
+	case *tl.AppExpr:
+		if p.angle(x) {
+			p.expr(x.Fun, termCtx)
+			p.print("<")
+			p.exprList(x.Args, ",", exprCtx)
+			p.print(">")
+			return
+		}
+
+		if ctx != exprCtx {
+			p.print("(")
+		}
+		p.expr(x.Fun, subExprCtx)
+		p.print(" ")
+		p.exprList(x.Args, " ", subExprCtx)
+		if ctx != exprCtx {
+			p.print(")")
+		}
+
+	case *tl.SumExpr:
+		if ctx == termCtx {
+			p.print("(")
+		}
+		p.expr(x.X, subExprCtx)
+		p.print("+")
+		p.expr(x.Y, subExprCtx)
+		if ctx == termCtx {
+			p.print(")")
+		}
+
+	case *tl.Repetition:
+		if x.Mult != nil {
+			p.expr(x.Mult, termCtx)
+			p.print("*")
+		}
+		p.print("[")
+		for i, arg := range x.Args {
+			if i > 0 {
+				p.print(" ")
+			}
+			p.arg(arg)
+		}
+		p.print("]")
+
+	default:
+		panic(fmt.Sprintf("printer: unexpected expression %T", x))
+	}
+}
+
+// angle reports whether the type application is printed in the angle bracket
+// form. Only applications of type identifiers can be written so.
+func (p *printer) angle(x *tl.AppExpr) bool {
+	if _, ok := x.Fun.(*tl.TypeIdent); !ok {
+		return false
+	}
+
+	switch p.Style {
+	case AngleStyle:
+		return true
+	case SpaceStyle:
+		return false
+	}
+	return x.Lang.IsValid()
+}
+
+// node prints the nodes which are neither declarations nor expressions.
+func (p *printer) node(node tl.Node) {
+	switch n := node.(type) {
+	case *tl.Comment:
+		p.print(n.Text)
+	case *tl.Ident:
+		p.print(n.Text())
+	case *tl.CombinatorId:
+		p.print(n.Id.Text())
+	case *tl.FullCombinatorId:
+		p.print(n.Id.Text())
+	case *tl.Arg:
+		p.arg(n)
+	case *tl.OptionalArg:
+		p.optArg(n)
+	case *tl.CondDef:
+		p.cond(n)
+	case *tl.ResultType:
+		p.result(n.Name.Name, n.Lang.IsValid(), n.Args)
+	case *tl.BoxedTypeIdent:
+		p.print(n.Name)
+	default:
+		panic(fmt.Sprintf("printer: unexpected node %T", node))
+	}
+}
+
+// String returns the canonical text of the node using the default config.
+func String(node tl.Node) string {
+	var buf strings.Builder
+	Fprint(&buf, node)
+	return buf.String()
+}
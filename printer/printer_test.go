@@ -0,0 +1,126 @@
+package printer
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+
+	"github.com/igungor/tl"
+)
+
+func parse(t *testing.T, src string) *tl.Program {
+	parser := tl.NewParser(bytes.NewBufferString(src))
+	program := parser.Parse()
+	if err := parser.Err(); err != nil {
+		t.Fatalf("parsing %q: %v", src, err)
+	}
+	return program
+}
+
+func TestFprint(t *testing.T) {
+	var tests = []struct {
+		style Style
+		src   string
+		want  string
+	}{
+		{KeepStyle, `int  ?=Int;`, "int ? = Int;\n"},
+		{KeepStyle, `vector#1cb5c415 {t:Type} # [ t ] = Vector t;`, "vector#1cb5c415 {t:Type} # [t] = Vector t;\n"},
+		{KeepStyle, `user id : int first_name:string=User;`, "user id:int first_name:string = User;\n"},
+		{KeepStyle, `msg flags:# x:flags.3?int y:flags.0? true = Msg;`, "msg flags:# x:flags.3?int y:flags.0?true = Msg;\n"},
+		{KeepStyle, `vectorTotal {t:Type} total_count:int vector:%( Vector t ) = VectorTotal t;`, "vectorTotal {t:Type} total_count:int vector:%(Vector t) = VectorTotal t;\n"},
+		{KeepStyle, `invokeAfterMsg {X:Type} msg_id:long query:!X = X;`, "invokeAfterMsg {X:Type} msg_id:long query:!X = X;\n"},
+		{KeepStyle, `getUser ( id : int ) = User;`, "getUser (id:int) = User;\n"},
+		{KeepStyle, `pair {X:Type} {Y:Type} a:X b:Y = Pair X Y;`, "pair {X:Type} {Y:Type} a:X b:Y = Pair X Y;\n"},
+		{KeepStyle, `t {n:#} n * [ x:int ] = T (n + 1);`, "t {n:#} n*[x:int] = T (n+1);\n"},
+		{KeepStyle, `Empty False; Final Bool; Vector int; Pair < Int , String >;`, "Empty False;\nFinal Bool;\nVector int;\nPair<Int,String>;\n"},
+
+		// type application styles
+		{KeepStyle, `a x:Vector<int> y:(Vector long) = Vector<A>;`, "a x:Vector<int> y:(Vector long) = Vector<A>;\n"},
+		{AngleStyle, `a x:Vector<int> y:(Vector long) = Vector<A>;`, "a x:Vector<int> y:Vector<long> = Vector<A>;\n"},
+		{SpaceStyle, `a x:Vector<int> y:(Vector long) = Vector<A>;`, "a x:(Vector int) y:(Vector long) = Vector A;\n"},
+		{SpaceStyle, `a x:Vector<Vector<int>> = Pair<Vector int, B>;`, "a x:(Vector (Vector int)) = Pair (Vector int) B;\n"},
+		{AngleStyle, `a x:(Vector (Vector int)) = Pair (Vector int) B;`, "a x:Vector<Vector<int>> = Pair<Vector<int>,B>;\n"},
+		{AngleStyle, `vector {t:Type} # [t] = Vector t;`, "vector {t:Type} # [t] = Vector<t>;\n"},
+
+		// sections, blank lines and comments
+		{
+			KeepStyle,
+			"// header\n\n\n\nint ? = Int; // builtin\nlong ? = Long;\n\n\n// bools\nboolTrue = Bool;\n---functions---\n// functions\nping = Pong;\n---types---  \npong = Pong;\n// EOF",
+			"// header\n\nint ? = Int; // builtin\nlong ? = Long;\n\n// bools\nboolTrue = Bool;\n\n---functions---\n\n// functions\nping = Pong;\n\n---types---\n\npong = Pong;\n// EOF\n",
+		},
+		{
+			KeepStyle,
+			"a = A;\n---functions---\nf = A;\n---types---\nb = B;\n---functions---\ng = B;\n---types---\n",
+			"a = A;\n\n---functions---\n\nf = A;\n\n---types---\n\nb = B;\n\n---functions---\n\ng = B;\n\n---types---\n",
+		},
+	}
+
+	for i, tt := range tests {
+		program := parse(t, tt.src)
+
+		var buf bytes.Buffer
+		cfg := &Config{Style: tt.style}
+		if err := cfg.Fprint(&buf, program); err != nil {
+			t.Fatal(err)
+		}
+
+		if got := buf.String(); got != tt.want {
+			t.Errorf("<%d> bad output for %q:\ngot  %q\nwant %q", i, tt.src, got, tt.want)
+		}
+	}
+}
+
+// TestFprint_idempotent checks that formatting the schema files is stable and
+// preserves all declarations.
+func TestFprint_idempotent(t *testing.T) {
+	for _, file := range []string{"../common.tl", "../schema.tl"} {
+		src, err := ioutil.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, style := range []Style{KeepStyle, AngleStyle, SpaceStyle} {
+			program := parse(t, string(src))
+
+			var out1, out2 bytes.Buffer
+			cfg := &Config{Style: style}
+			cfg.Fprint(&out1, program)
+
+			reparsed := parse(t, out1.String())
+			cfg.Fprint(&out2, reparsed)
+
+			if out1.String() != out2.String() {
+				t.Errorf("%v: formatting is not idempotent with style %d", file, style)
+			}
+
+			if len(reparsed.Constructors) != len(program.Constructors) || len(reparsed.Functions) != len(program.Functions) {
+				t.Errorf("%v: declarations are lost with style %d", file, style)
+			}
+			if len(reparsed.Comments) != len(program.Comments) {
+				t.Errorf("%v: comments are lost with style %d", file, style)
+			}
+		}
+	}
+}
+
+func TestString(t *testing.T) {
+	program := parse(t, `getUsers#2d84d5f5 (Vector int) = Vector User;`)
+	decl := program.Constructors[0].(*tl.CombDecl)
+
+	var tests = []struct {
+		node tl.Node
+		want string
+	}{
+		{decl, "getUsers#2d84d5f5 (Vector int) = Vector User;"},
+		{decl.Args[0], "(Vector int)"},
+		{decl.Args[0].Type.(*tl.ParenExpr).X, "Vector int"},
+		{decl.Result, "Vector User"},
+		{decl.Id, "getUsers#2d84d5f5"},
+	}
+
+	for i, tt := range tests {
+		if got := String(tt.node); got != tt.want {
+			t.Errorf("<%d> got %q, want %q", i, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package tl
+
+// Reachability describes the types reachable from the functions of a schema
+// through their fields and result types, transitively through the fields of
+// the constructors of the reached types.
+type Reachability struct {
+	s       *Schema
+	reached map[*Type]*PathStep // first step reaching each type
+}
+
+// PathStep is a step of the path from a function to a type.
+type PathStep struct {
+	Function    *Function    // function the type is referenced by, or nil
+	Constructor *Constructor // constructor the type is referenced by, or nil
+	Field       string       // field referencing the type, empty for result types
+	Type        *Type        // reached type
+}
+
+// From returns the name of the function or constructor the type is
+// referenced by.
+func (p *PathStep) From() string {
+	if p.Function != nil {
+		return p.Function.name
+	}
+	return p.Constructor.name
+}
+
+func (p *PathStep) String() string {
+	if p.Field == "" {
+		return p.From() + " = " + p.Type.name
+	}
+	return p.From() + "." + p.Field + " -> " + p.Type.name
+}
+
+// Reachable computes the types reachable from the functions of s. Types are
+// reached along the shortest path from the first function in declaration
+// order.
+func (s *Schema) Reachable() *Reachability {
+	r := &Reachability{s: s, reached: make(map[*Type]*PathStep)}
+
+	var queue []*Type
+	visit := func(from PathStep, ref *TypeRef) {
+		for _, t := range refTypes(ref) {
+			if _, ok := r.reached[t]; !ok {
+				step := from
+				step.Type = t
+				r.reached[t] = &step
+				queue = append(queue, t)
+			}
+		}
+	}
+
+	for _, f := range s.Functions {
+		for _, field := range f.Fields {
+			visit(PathStep{Function: f, Field: fieldName(field)}, field.Type)
+		}
+		visit(PathStep{Function: f}, f.Result)
+	}
+
+	for len(queue) > 0 {
+		t := queue[0]
+		queue = queue[1:]
+		for _, c := range t.Constructors {
+			for _, field := range c.Fields {
+				visit(PathStep{Constructor: c, Field: fieldName(field)}, field.Type)
+			}
+		}
+	}
+
+	return r
+}
+
+// fieldName returns the name of the field for paths, e.g. _ for anonymous
+// fields.
+func fieldName(f *Field) string {
+	if f.Name == "" {
+		return "_"
+	}
+	return f.Name
+}
+
+// refTypes returns the types referenced by ref, including the types of type
+// arguments and of the fields of repetitions.
+func refTypes(ref *TypeRef) []*Type {
+	if ref == nil {
+		return nil
+	}
+
+	var types []*Type
+	switch obj := ref.Object.(type) {
+	case *Type:
+		types = append(types, obj)
+	case *Constructor:
+		types = append(types, obj.Type)
+	}
+	for _, arg := range ref.Args {
+		types = append(types, refTypes(arg)...)
+	}
+	for _, f := range ref.Fields {
+		types = append(types, refTypes(f.Type)...)
+	}
+	return types
+}
+
+// Contains reports whether t is reachable.
+func (r *Reachability) Contains(t *Type) bool {
+	_, ok := r.reached[t]
+	return ok
+}
+
+// Types returns the reachable types in declaration order.
+func (r *Reachability) Types() []*Type {
+	var types []*Type
+	for _, t := range r.s.Types {
+		if r.Contains(t) {
+			types = append(types, t)
+		}
+	}
+	return types
+}
+
+// Unused returns the types which are not reachable from any function, in
+// declaration order.
+func (r *Reachability) Unused() []*Type {
+	var types []*Type
+	for _, t := range r.s.Types {
+		if !r.Contains(t) {
+			types = append(types, t)
+		}
+	}
+	return types
+}
+
+// Path returns the path from a function to t, which explains why t is
+// reachable. It returns nil if t is not reachable.
+func (r *Reachability) Path(t *Type) []*PathStep {
+	var path []*PathStep
+	for {
+		step, ok := r.reached[t]
+		if !ok {
+			return nil
+		}
+		path = append([]*PathStep{step}, path...)
+
+		if step.Constructor == nil {
+			// reached from a function
+			return path
+		}
+		t = step.Constructor.Type
+	}
+}
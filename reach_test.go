@@ -0,0 +1,79 @@
+package tl
+
+import "testing"
+
+func TestSchema_Reachable(t *testing.T) {
+	const src = `
+vector#1cb5c415 {t:Type} # [ t ] = Vector t;
+messages.messages#8c718e87 messages:Vector<Message> users:Vector<User> = messages.Messages;
+message#22eb6aba id:int media:MessageMedia = Message;
+messageMediaEmpty#3ded6320 = MessageMedia;
+messageMediaPhoto#c8c45a2a photo:Photo = MessageMedia;
+photo#22b56751 id:long = Photo;
+user#1 id:int = User;
+geoPoint#2 long:double lat:double = GeoPoint;
+chat#3 id:int photo:Photo = Chat;
+---functions---
+messages.getHistory#92a1df2f offset:int = messages.Messages;
+`
+
+	schema, err := NewSchema(parseString(t, src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := schema.Reachable()
+
+	var unused []string
+	for _, t := range r.Unused() {
+		unused = append(unused, t.Name())
+	}
+	if len(unused) != 2 || unused[0] != "GeoPoint" || unused[1] != "Chat" {
+		t.Errorf("got unused types %q, expected [GeoPoint Chat]", unused)
+	}
+	if n := len(r.Types()); n != 6 {
+		t.Errorf("got %d reachable types, expected 6", n)
+	}
+
+	var path []string
+	for _, step := range r.Path(schema.Type("Photo")) {
+		path = append(path, step.String())
+	}
+	want := []string{
+		"messages.getHistory = messages.Messages",
+		"messages.messages.messages -> Message",
+		"message.media -> MessageMedia",
+		"messageMediaPhoto.photo -> Photo",
+	}
+	if len(path) != len(want) {
+		t.Fatalf("got path %q, expected %q", path, want)
+	}
+	for i := range want {
+		if path[i] != want[i] {
+			t.Errorf("<%d> got step %q, expected %q", i, path[i], want[i])
+		}
+	}
+
+	if r.Path(schema.Type("Chat")) != nil {
+		t.Errorf("expected no path to Chat")
+	}
+}
+
+// TestReachability_Path_nameClash checks paths from a function named like a
+// constructor of the type it reaches.
+func TestReachability_Path_nameClash(t *testing.T) {
+	const src = `
+user#1 id:int = User;
+---functions---
+user#2 id:int = User;
+`
+
+	schema, err := NewSchema(parseString(t, src))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := schema.Reachable().Path(schema.Type("User"))
+	if len(path) != 1 || path[0].Function != schema.Function("user") || path[0].String() != "user = User" {
+		t.Errorf("got path %v, expected [user = User]", path)
+	}
+}
@@ -0,0 +1,130 @@
+package tl
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// analyzeTypes marks the recursive and the well-founded types of s.
+func (s *Schema) analyzeTypes() {
+	// edges of the type graph, the types referenced by the fields of the
+	// constructors of each type
+	refs := make(map[*Type][]*Type)
+	for _, t := range s.Types {
+		for _, c := range t.Constructors {
+			for _, f := range c.Fields {
+				refs[t] = append(refs[t], refTypes(f.Type)...)
+			}
+		}
+	}
+
+	for _, t := range s.Types {
+		t.Recursive = reaches(refs, t, t)
+	}
+
+	// a constructor has finite values if all of its required fields have
+	// finite values. Iterate until no more constructors are found.
+	finite := make(map[*Constructor]bool)
+	for changed := true; changed; {
+		changed = false
+		for _, c := range s.Constructors {
+			if finite[c] || !s.fieldsFinite(c.Fields, finite) {
+				continue
+			}
+			finite[c] = true
+			c.Type.WellFounded = true
+			changed = true
+		}
+	}
+}
+
+// reaches reports whether to is reachable from the types referenced by from.
+func reaches(refs map[*Type][]*Type, from, to *Type) bool {
+	seen := make(map[*Type]bool)
+	var visit func(t *Type) bool
+	visit = func(t *Type) bool {
+		for _, u := range refs[t] {
+			if u == to {
+				return true
+			}
+			if !seen[u] {
+				seen[u] = true
+				if visit(u) {
+					return true
+				}
+			}
+		}
+		return false
+	}
+	return visit(from)
+}
+
+// fieldsFinite reports whether values of the given fields can be constructed
+// from the constructors known to have finite values. Conditional fields may
+// be absent and don't count.
+func (s *Schema) fieldsFinite(fields []*Field, finite map[*Constructor]bool) bool {
+	for _, f := range fields {
+		if f.Optional || f.Cond != nil {
+			continue
+		}
+		if !s.refFinite(f.Type, finite) {
+			return false
+		}
+	}
+	return true
+}
+
+// refFinite reports whether the referenced type has finite values. Type
+// arguments are not taken into account, e.g. Vector<T> has finite values
+// regardless of T since vectors may be empty.
+func (s *Schema) refFinite(ref *TypeRef, finite map[*Constructor]bool) bool {
+	if ref.Fields != nil {
+		// repetitions with a possibly zero multiplicity may be empty
+		n, ok := ref.Mult.natConst()
+		if !ok || n == 0 {
+			return true
+		}
+		return s.fieldsFinite(ref.Fields, finite)
+	}
+
+	switch obj := ref.Object.(type) {
+	case *Type:
+		return obj.WellFounded || isPredeclared(obj)
+	case *Constructor:
+		if _, ok := obj.Decl.(*CombDecl); !ok {
+			return true // builtin
+		}
+		return finite[obj]
+	}
+	return true
+}
+
+// isPredeclared reports whether t is a predeclared type without declared
+// constructors, e.g. Int.
+func isPredeclared(t *Type) bool {
+	return !t.Pos().IsValid()
+}
+
+// natConst returns the value of a nat constant reference.
+func (t *TypeRef) natConst() (int, bool) {
+	if t == nil {
+		return 0, false
+	}
+	x, ok := t.Expr.(*NatConst)
+	if !ok {
+		return 0, false
+	}
+	n, err := strconv.Atoi(x.Value)
+	return n, err == nil
+}
+
+// checkWellFounded reports the types of s which have constructors but no
+// finite values, since every constructor requires a value of such a type.
+func (s *Schema) checkWellFounded(errs *ErrorList) {
+	for _, t := range s.Types {
+		if len(t.Constructors) == 0 || t.WellFounded {
+			continue
+		}
+		errs.Add(t.Pos(), fmt.Sprintf("type %s has no finite values, every constructor requires a value of a type without finite values", t.name))
+	}
+}
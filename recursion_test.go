@@ -0,0 +1,63 @@
+package tl
+
+import "testing"
+
+func TestSchema_recursiveTypes(t *testing.T) {
+	const src = `
+vector#1cb5c415 {t:Type} # [ t ] = Vector t;
+empty_tree = IntTree;
+int_tree IntTree int IntTree = IntTree;
+node children:Vector<Node> = Node;
+loop next:Loop = Loop;
+ping pong:Pong = Ping;
+pong ping:Ping = Pong;
+chain links:3*[Loop] = Chain;
+maybeLoop flags:# next:flags.0?Loop = MaybeLoop;
+user id:int = User;
+`
+
+	schema, err := NewSchema(parseString(t, src))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		typ         string
+		recursive   bool
+		wellFounded bool
+	}{
+		{"Vector", false, true},
+		{"IntTree", true, true},
+		{"Node", true, true},
+		{"Loop", true, false},
+		{"Ping", true, false},
+		{"Pong", true, false},
+		{"Chain", false, false},
+		{"MaybeLoop", false, true},
+		{"User", false, true},
+	}
+
+	for _, tt := range tests {
+		typ := schema.Type(tt.typ)
+		if typ.Recursive != tt.recursive || typ.WellFounded != tt.wellFounded {
+			t.Errorf("%s: got recursive %v, well-founded %v, expected %v, %v",
+				tt.typ, typ.Recursive, typ.WellFounded, tt.recursive, tt.wellFounded)
+		}
+	}
+}
+
+func TestChecker_wellFounded(t *testing.T) {
+	const src = `
+empty_tree = IntTree;
+int_tree IntTree int IntTree = IntTree;
+loop next:Loop = Loop;
+`
+
+	var checker Checker
+	errs := checker.Check(parseString(t, src))
+
+	want := "4:18: type Loop has no finite values, every constructor requires a value of a type without finite values"
+	if len(errs) != 1 || errs[0].Error() != want {
+		t.Errorf("got errors %v, expected %q", errs, want)
+	}
+}
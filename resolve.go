@@ -0,0 +1,231 @@
+package tl
+
+import "fmt"
+
+// An Object is a named entity a type reference denotes: a *Type, a
+// *Constructor, a *Builtin or a *TypeVar.
+type Object interface {
+	Name() string
+	Pos() Pos // position of the declaration; invalid for predeclared objects
+}
+
+// Builtin represents a predeclared object which is not declared by any
+// combinator: # (the natural numbers) and Type (the type of types).
+type Builtin struct {
+	name string
+}
+
+// TypeVar represents a field of a combinator referred to by other fields or
+// by the result type, e.g. the t in `vector {t:Type} # [t] = Vector t` or the
+// n in `tuple {t:Type} {n:#} [t] = Tuple t n`.
+type TypeVar struct {
+	Ident *Ident // declaring identifier
+	Type  Expr   // type of the field, e.g. Type or #
+}
+
+func (b *Builtin) Name() string     { return b.name }
+func (t *Type) Name() string        { return t.name }
+func (c *Constructor) Name() string { return c.name }
+func (v *TypeVar) Name() string     { return v.Ident.Text() }
+
+func (b *Builtin) Pos() Pos { return Pos{} }
+func (t *Type) Pos() Pos    { return t.pos }
+func (c *Constructor) Pos() Pos {
+	if c.Decl == nil {
+		return Pos{}
+	}
+	return c.Decl.Pos()
+}
+func (v *TypeVar) Pos() Pos { return v.Ident.Pos() }
+
+// Predeclared objects.
+var (
+	Nat      = &Builtin{"#"}
+	TypeType = &Builtin{"Type"}
+)
+
+// builtinTypes lists the predeclared bare types and their boxed types. They
+// can be used without being declared with a builtin-combinator-decl.
+var builtinTypes = []struct{ bare, boxed string }{
+	{"int", "Int"},
+	{"long", "Long"},
+	{"double", "Double"},
+	{"string", "String"},
+}
+
+// newUniverse returns the scope of the predeclared objects.
+func newUniverse() map[string]Object {
+	universe := map[string]Object{
+		Nat.name:      Nat,
+		TypeType.name: TypeType,
+	}
+
+	for _, b := range builtinTypes {
+		t := &Type{name: b.boxed}
+		c := &Constructor{name: b.bare, ID: computeCRC32(b.bare + " ? = " + b.boxed), Type: t}
+		t.Constructors = []*Constructor{c}
+		universe[b.bare] = c
+		universe[b.boxed] = t
+	}
+
+	return universe
+}
+
+// Lookup returns the object with the given name: a type, a constructor or a
+// predeclared object. It returns nil if there is no such object.
+func (s *Schema) Lookup(name string) Object {
+	if t, ok := s.types[name]; ok {
+		return t
+	}
+	if c, ok := s.constructors[name]; ok {
+		return c
+	}
+	if obj, ok := s.universe[name]; ok {
+		return obj
+	}
+	return nil
+}
+
+// ObjectOf returns the object denoted by the given type reference, which is
+// one of *TypeIdent, *BoxedTypeIdent or *Var. It returns nil if the reference
+// is not resolved.
+func (s *Schema) ObjectOf(ref Node) Object {
+	return s.Uses[ref]
+}
+
+// resolver links the type references of declarations to their objects.
+type resolver struct {
+	s     *Schema
+	errs  *ErrorList
+	scope map[string]*TypeVar // fields of the combinator being resolved
+}
+
+// resolve resolves all declarations of prog.
+func (s *Schema) resolve(prog *Program, errs *ErrorList) {
+	r := &resolver{s: s, errs: errs}
+
+	for _, decls := range [][]Declaration{prog.Constructors, prog.Functions, prog.Types} {
+		for _, decl := range decls {
+			r.decl(decl)
+		}
+	}
+}
+
+func (r *resolver) decl(decl Declaration) {
+	switch d := decl.(type) {
+	case *CombDecl:
+		r.scope = make(map[string]*TypeVar)
+		defer func() { r.scope = nil }()
+
+		for _, arg := range d.OptArgs {
+			r.expr(arg.Type)
+			r.declare(arg.Names, arg.Type)
+		}
+		r.args(d.Args)
+		r.boxed(d.Result.Name)
+		r.exprList(d.Result.Args)
+
+	case *BuiltinCombDecl:
+		r.boxed(d.Result)
+
+	case *PartialTypeAppDecl:
+		r.boxed(d.Name)
+		r.exprList(d.Args)
+
+	case *PartialCombAppDecl:
+		name := d.Id.Id.Text()
+		if _, ok := r.s.Lookup(name).(*Constructor); !ok {
+			r.errs.Add(d.Pos(), fmt.Sprintf("undefined: %s", name))
+		}
+		r.exprList(d.Args)
+
+	case *FinalDecl:
+		// undefined types are reported by declareFinal
+		if t, ok := r.s.types[d.Name.Name]; ok {
+			r.s.Uses[d.Name] = t
+		}
+	}
+}
+
+func (r *resolver) args(list []*Arg) {
+	for _, arg := range list {
+		if rep, ok := arg.Type.(*Repetition); ok {
+			if rep.Mult != nil {
+				r.expr(rep.Mult)
+			}
+			r.args(rep.Args)
+		} else {
+			r.expr(arg.Type)
+		}
+		r.declare(arg.Names, arg.Type)
+	}
+}
+
+// declare adds the given field names to the scope of the combinator.
+func (r *resolver) declare(names []*Ident, typ Expr) {
+	for _, name := range names {
+		if name.Name.Token != ItemUnderscore {
+			r.scope[name.Text()] = &TypeVar{Ident: name, Type: typ}
+		}
+	}
+}
+
+// boxed resolves a boxed type identifier, which is either a type or a type
+// variable, e.g. the X in `invokeWithLayer {X:Type} layer:int query:!X = X`.
+func (r *resolver) boxed(ident *BoxedTypeIdent) {
+	if v, ok := r.scope[ident.Name]; ok {
+		r.s.Uses[ident] = v
+		return
+	}
+
+	obj, ok := r.s.Lookup(ident.Name).(*Type)
+	if !ok {
+		r.errs.Add(ident.Pos(), fmt.Sprintf("undefined: %s", ident.Name))
+		return
+	}
+	r.s.Uses[ident] = obj
+}
+
+func (r *resolver) exprList(list []Expr) {
+	for _, x := range list {
+		r.expr(x)
+	}
+}
+
+// expr resolves the type references of a type expression.
+func (r *resolver) expr(x Expr) {
+	Inspect(x, func(n Node) bool {
+		switch n := n.(type) {
+		case *TypeIdent:
+			obj := r.s.Lookup(n.Name)
+			if obj == nil {
+				r.errs.Add(n.Pos(), fmt.Sprintf("undefined: %s", n.Name))
+				return false
+			}
+			r.s.Uses[n] = obj
+
+		case *Var:
+			v, ok := r.scope[n.Name]
+			if !ok {
+				r.errs.Add(n.Pos(), fmt.Sprintf("undefined: %s", n.Name))
+				return false
+			}
+			r.s.Uses[n] = v
+
+		case *BareType:
+			r.expr(n.X)
+			r.bare(n)
+			return false
+
+		case *Repetition:
+			// fields of repeated arguments are declared in the scope of
+			// the combinator as well.
+			if n.Mult != nil {
+				r.expr(n.Mult)
+			}
+			r.args(n.Args)
+			return false
+		}
+		return true
+	})
+}
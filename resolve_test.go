@@ -0,0 +1,131 @@
+package tl
+
+import (
+	"os"
+	"testing"
+)
+
+func TestSchema_resolve(t *testing.T) {
+	const src = `
+int ? = Int;
+vector#1cb5c415 {t:Type} # [ t ] = Vector t;
+inputPhotoEmpty#1cd7bf0d = InputPhoto;
+inputMediaPhoto#8f2ab2ec id:InputPhoto = InputMedia;
+inputMediaPhotos ids:Vector<inputPhotoEmpty> n:# longs:n*[long] = InputMedia;
+---functions---
+invokeWithLayer#da9b0d0d {X:Type} layer:int query:!X = X;
+`
+
+	schema, err := NewSchema(parseString(t, src))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	uses := make(map[string]Object)
+	for ref, obj := range schema.Uses {
+		var name string
+		switch ref := ref.(type) {
+		case *TypeIdent:
+			name = ref.Name
+		case *BoxedTypeIdent:
+			name = ref.Name
+		case *Var:
+			name = ref.Name
+		}
+		uses[name] = obj
+	}
+
+	tests := []struct {
+		name string
+		obj  Object
+	}{
+		{"Type", TypeType},
+		{"#", Nat},
+		{"t", nil}, // checked below
+		{"int", schema.Constructor("int")},
+		{"long", schema.Lookup("long")},
+		{"InputPhoto", schema.Type("InputPhoto")},
+		{"inputPhotoEmpty", schema.Constructor("inputPhotoEmpty")},
+		{"Vector", schema.Type("Vector")},
+		{"InputMedia", schema.Type("InputMedia")},
+	}
+
+	for _, tt := range tests {
+		obj, ok := uses[tt.name]
+		if !ok {
+			t.Errorf("%s is not resolved", tt.name)
+			continue
+		}
+		if tt.obj != nil && obj != tt.obj {
+			t.Errorf("%s resolved to %#v, expected %#v", tt.name, obj, tt.obj)
+		}
+	}
+
+	for _, name := range []string{"t", "n", "X"} {
+		v, ok := uses[name].(*TypeVar)
+		if !ok {
+			t.Errorf("%s resolved to %#v, expected a type variable", name, uses[name])
+			continue
+		}
+		if v.Name() != name {
+			t.Errorf("bad type variable for %s: %s", name, v.Name())
+		}
+	}
+
+	if pos := uses["InputPhoto"].Pos(); pos.Line != 4 || pos.Column != 28 {
+		t.Errorf("bad declaration position of InputPhoto: %v", pos)
+	}
+	if obj := schema.Lookup("long"); obj.Pos().IsValid() {
+		t.Errorf("predeclared long has a position: %v", obj.Pos())
+	}
+}
+
+func TestSchema_resolveUndefined(t *testing.T) {
+	const src = `
+inputMediaPhoto#8f2ab2ec id:InputPhoto = InputMedia;
+inputMediaDocument id:inputDocument caption:string = InputMedia;
+---functions---
+getMedia id:int = Media;
+`
+
+	_, err := NewSchema(parseString(t, src))
+
+	errs, ok := err.(ErrorList)
+	if !ok {
+		t.Fatalf("expected ErrorList, got %v", err)
+	}
+
+	want := []string{
+		"2:29: undefined: InputPhoto",
+		"3:23: undefined: inputDocument",
+		"5:19: undefined: Media",
+	}
+	if len(errs) != len(want) {
+		t.Fatalf("expected %d errors, got %v", len(want), err)
+	}
+	for i, e := range errs {
+		if e.Error() != want[i] {
+			t.Errorf("<%d> got %q, expected %q", i, e.Error(), want[i])
+		}
+	}
+}
+
+func TestSchema_resolveFiles(t *testing.T) {
+	for _, filename := range []string{"common.tl", "schema.tl"} {
+		f, err := os.Open(filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		parser := NewParser(f)
+		program := parser.Parse()
+		f.Close()
+		if err := parser.Err(); err != nil {
+			t.Fatalf("%s: %v", filename, err)
+		}
+
+		if _, err := NewSchema(program); err != nil {
+			t.Errorf("%s: %v", filename, err)
+		}
+	}
+}
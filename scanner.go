@@ -17,19 +17,24 @@ type Scanner struct {
 	r   *bufio.Reader
 	ch  rune  // one rune look-ahead
 	err error // sticky error
+
+	pos    Pos // position of the look-ahead rune
+	tokPos Pos // position of the most recently scanned token
 }
 
 // NewScanner returns a Scanner which tokenizes a TL program
 func NewScanner(r io.Reader) *Scanner {
 	return &Scanner{
-		r:  bufio.NewReader(r),
-		ch: notReadYet,
+		r:   bufio.NewReader(r),
+		ch:  notReadYet,
+		pos: Pos{Offset: 0, Line: 1, Column: 1},
 	}
 }
 
 // Scan returns the next token from the underlying reader.
 func (s *Scanner) Scan() Token {
 	ch := s.peek()
+	s.tokPos = s.pos
 
 	switch {
 	case isWhitespace(ch):
@@ -45,8 +50,9 @@ func (s *Scanner) Scan() Token {
 			return Token{Token: ItemEOF}
 		case '/':
 			if s.ch == '/' {
-				s.r.ReadBytes('\n')
+				return s.scanComment()
 			}
+			return Token{ItemIllegal, string(ch)}
 		case '-':
 			return s.scanTripleMinus()
 		case '#':
@@ -93,17 +99,24 @@ func (s *Scanner) Scan() Token {
 			return Token{ItemIllegal, string(ch)}
 		}
 	}
-
-	panic("unreachable")
 }
 
 // Next reads and returns the next rune from the underlying reader.
 func (s *Scanner) Next() rune {
 	next := s.peek()
+	if next != eof {
+		s.pos = s.pos.advance(string(next))
+	}
 	s.ch = s.next()
 	return next
 }
 
+// Pos returns the position of the first character of the most recently
+// scanned token.
+func (s *Scanner) Pos() Pos {
+	return s.tokPos
+}
+
 // Err returns the first non-EOF error that was encountered by the Scanner.
 func (s *Scanner) Err() error {
 	if s.err == io.EOF {
@@ -123,6 +136,20 @@ func (s *Scanner) scanTripleMinus() Token {
 	return Token{ItemIllegal, string("-") + string(minus1) + string(minus2)}
 }
 
+// scanComment consumes a line comment. The leading '/' is already consumed and
+// the trailing newline is not part of the comment.
+func (s *Scanner) scanComment() Token {
+	var buf bytes.Buffer
+
+	buf.WriteRune('/')
+	for s.ch != '\n' && s.ch != eof {
+		buf.WriteRune(s.ch)
+		s.Next()
+	}
+
+	return Token{ItemComment, buf.String()}
+}
+
 // scanWhitespace consumes the current rune and all contiguous whitespaces.
 func (s *Scanner) scanWhitespace() Token {
 	var buf bytes.Buffer
@@ -174,8 +201,9 @@ func (s *Scanner) scanIdent() Token {
 		s.Next()
 	}
 
-	// handle namespace
-	if s.ch == '.' {
+	// handle namespace. a dot which is not followed by a letter belongs to
+	// the next token, e.g. flags.3?int
+	if s.ch == '.' && s.peekLetter() {
 		buf.WriteRune(s.ch)
 
 		s.Next()
@@ -192,30 +220,27 @@ func (s *Scanner) scanIdent() Token {
 		}
 
 		// lc-ident-ns
-		if isLowerLetter(s.ch) {
-			// consume all ident-chars
-			for isIdentChar(s.ch) {
-				buf.WriteRune(s.ch)
-				s.Next()
-			}
-		} else {
-			s.setErr(fmt.Errorf("expected letter, got %q", s.ch))
+		for isIdentChar(s.ch) {
+			buf.WriteRune(s.ch)
+			s.Next()
 		}
 	}
 
 	// lc-ident-full
 	if s.ch == '#' {
 		buf.WriteRune(s.ch)
+		s.Next()
 
-		// expect 8 hex-digits
-		for i := 0; i < 8; i++ {
-			s.Next()
-			if !isHexDigit(s.ch) {
-				s.setErr(fmt.Errorf("expected hexdigit, got %q", s.ch))
-			}
+		// expect at most 8 hex-digits. leading zeros are usually omitted,
+		// e.g. storage.fileJpeg#7efe0e
+		var n int
+		for ; n < 8 && isHexDigit(s.ch); n++ {
 			buf.WriteRune(s.ch)
+			s.Next()
+		}
+		if n == 0 {
+			s.setErr(&Error{Pos: s.pos, Msg: fmt.Sprintf("expected hexdigit, got %q", s.ch)})
 		}
-		s.Next()
 	}
 
 	return Token{ItemLowerIdent, buf.String()}
@@ -241,6 +266,13 @@ func (s *Scanner) next() rune {
 	return ch
 }
 
+// peekLetter reports whether the rune following the look-ahead rune is a
+// letter. It does not advance the scanner.
+func (s *Scanner) peekLetter() bool {
+	b, err := s.r.Peek(1)
+	return err == nil && isLetter(rune(b[0]))
+}
+
 // peek return the next rune in the reader without advancing the scanner.
 func (s *Scanner) peek() rune {
 	if s.ch == notReadYet {
@@ -295,4 +327,4 @@ func isIdentChar(ch rune) bool { return isLetter(ch) || isDigit(ch) || ch == '_'
 
 // isWhitespace reports whether the rune is a valid whitespace separator.
 //
-func isWhitespace(ch rune) bool { return ch == ' ' || ch == '\t' || ch == '\n' }
+func isWhitespace(ch rune) bool { return ch == ' ' || ch == '\t' || ch == '\n' || ch == '\r' }
@@ -65,6 +65,11 @@ func TestScanner_Scan(t *testing.T) {
 		{s: `users.user#decafbad`, tok: Token{Token: ItemLowerIdent, Literal: "users.user#decafbad"}},
 		{s: `User`, tok: Token{Token: ItemUpperIdent, Literal: "User"}},
 		{s: `users.User`, tok: Token{Token: ItemUpperIdent, Literal: "users.User"}},
+		{s: `storage.fileJpeg#7efe0e`, tok: Token{Token: ItemLowerIdent, Literal: "storage.fileJpeg#7efe0e"}},
+
+		// comments
+		{s: `// Vector`, tok: Token{Token: ItemComment, Literal: "// Vector"}},
+		{s: "//\nint", tok: Token{Token: ItemComment, Literal: "//"}},
 
 		// Illegal
 		{s: `--a`, tok: Token{Token: ItemIllegal, Literal: "--a"}},
@@ -105,6 +110,32 @@ func TestScanner_Scan(t *testing.T) {
 			{ItemSemicolon, ";"},
 		},
 		},
+		{`x:flags.3?int`, []Token{
+			{ItemLowerIdent, "x"},
+			{ItemColon, ":"},
+			{ItemLowerIdent, "flags"},
+			{ItemDot, "."},
+			{ItemNatConst, "3"},
+			{ItemQuestionMark, "?"},
+			{ItemLowerIdent, "int"},
+		},
+		},
+		{"true = True; // unit\n---functions---", []Token{
+			{ItemLowerIdent, "true"},
+			{ItemWhitespace, " "},
+			{ItemEquals, "="},
+			{ItemWhitespace, " "},
+			{ItemUpperIdent, "True"},
+			{ItemSemicolon, ";"},
+			{ItemWhitespace, " "},
+			{ItemComment, "// unit"},
+			{ItemWhitespace, "\n"},
+			{ItemTripleMinus, "---"},
+			{ItemLowerIdent, "functions"},
+			{ItemTripleMinus, "---"},
+			{ItemEOF, ""},
+		},
+		},
 	}
 
 	for _, mt := range multitokentests {
@@ -123,3 +154,43 @@ func TestScanner_Scan(t *testing.T) {
 		}
 	}
 }
+
+func TestScanner_Pos(t *testing.T) {
+	const src = "int ? = Int;\n// comment\n  user#d23c81a3 id:int = User;"
+
+	var tests = []struct {
+		tok Token
+		pos Pos
+	}{
+		{Token{ItemLowerIdent, "int"}, Pos{Offset: 0, Line: 1, Column: 1}},
+		{Token{ItemQuestionMark, "?"}, Pos{Offset: 4, Line: 1, Column: 5}},
+		{Token{ItemEquals, "="}, Pos{Offset: 6, Line: 1, Column: 7}},
+		{Token{ItemUpperIdent, "Int"}, Pos{Offset: 8, Line: 1, Column: 9}},
+		{Token{ItemSemicolon, ";"}, Pos{Offset: 11, Line: 1, Column: 12}},
+		{Token{ItemComment, "// comment"}, Pos{Offset: 13, Line: 2, Column: 1}},
+		{Token{ItemLowerIdent, "user#d23c81a3"}, Pos{Offset: 26, Line: 3, Column: 3}},
+		{Token{ItemLowerIdent, "id"}, Pos{Offset: 40, Line: 3, Column: 17}},
+		{Token{ItemColon, ":"}, Pos{Offset: 42, Line: 3, Column: 19}},
+		{Token{ItemLowerIdent, "int"}, Pos{Offset: 43, Line: 3, Column: 20}},
+		{Token{ItemEquals, "="}, Pos{Offset: 47, Line: 3, Column: 24}},
+		{Token{ItemUpperIdent, "User"}, Pos{Offset: 49, Line: 3, Column: 26}},
+		{Token{ItemSemicolon, ";"}, Pos{Offset: 53, Line: 3, Column: 30}},
+		{Token{ItemEOF, ""}, Pos{Offset: 54, Line: 3, Column: 31}},
+	}
+
+	scanner := NewScanner(bytes.NewBufferString(src))
+	for _, tt := range tests {
+		token := scanner.Scan()
+		for token.Token == ItemWhitespace {
+			token = scanner.Scan()
+		}
+
+		if !reflect.DeepEqual(token, tt.tok) {
+			t.Fatalf("got %#v, expected %#v", token, tt.tok)
+		}
+
+		if scanner.Pos() != tt.pos {
+			t.Errorf("bad position for %q: got %v, expected %v", tt.tok.Literal, scanner.Pos(), tt.pos)
+		}
+	}
+}
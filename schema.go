@@ -0,0 +1,278 @@
+package tl
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Schema is the semantic model of a TL program: the declared types along with
+// their constructors, and the functions.
+type Schema struct {
+	Types        []*Type
+	Constructors []*Constructor
+	Functions    []*Function
+	Instances    []*Instance // partial applications, e.g. Vector int
+
+	// Uses maps the type references of the program, *TypeIdent,
+	// *BoxedTypeIdent and *Var nodes, to the objects they denote.
+	Uses map[Node]Object
+
+	types          map[string]*Type
+	constructors   map[string]*Constructor
+	functions      map[string]*Function
+	constructorIDs map[uint32]*Constructor
+	functionIDs    map[uint32]*Function
+	universe       map[string]Object
+}
+
+// Type represents a boxed type, e.g. InputPeer.
+type Type struct {
+	name         string
+	pos          Pos // position of the first result type naming it
+	final        Pos // position of the Final or Empty declaration, if any
+	Constructors []*Constructor
+
+	// Recursive reports whether the type refers to itself, directly or
+	// through other types, in the fields of its constructors, e.g.
+	// IntTree in int_tree IntTree int IntTree = IntTree.
+	Recursive bool
+
+	// WellFounded reports whether the type has finite values, i.e. at least
+	// one of its constructors doesn't require a value of the type itself or
+	// of another type without finite values.
+	WellFounded bool
+}
+
+// Constructor represents a constructor of a boxed type, e.g. inputPeerSelf.
+type Constructor struct {
+	name   string
+	ID     uint32   // explicit or computed combinator-name
+	Type   *Type    // the boxed result type
+	Fields []*Field // optional fields first; nil for builtins
+
+	Decl Combinator // *CombDecl or *BuiltinCombDecl
+}
+
+// Function represents a function, e.g. auth.checkPhone.
+type Function struct {
+	name   string
+	ID     uint32   // explicit or computed combinator-name
+	Fields []*Field // optional fields first
+	Result *TypeRef // result type, e.g. Vector<User>
+
+	Decl *CombDecl
+}
+
+// NewSchema builds the schema of the given programs, e.g. the files of a
+// multi-file schema in order. Constructors are grouped by their boxed result
+// types in declaration order. Every type reference is resolved to its
+// declaration, see Schema.Uses; references to undeclared types are reported
+// as errors.
+//
+// Final declarations are honoured: `Empty T` declares the type T without
+// constructors, `New T` declares T and fails if it already exists, and
+// `Final T` forbids adding constructors to T later on.
+func NewSchema(progs ...*Program) (*Schema, error) {
+	s := &Schema{
+		types:        make(map[string]*Type),
+		constructors: make(map[string]*Constructor),
+		functions:    make(map[string]*Function),
+		universe:     newUniverse(),
+		Uses:         make(map[Node]Object),
+
+		constructorIDs: make(map[uint32]*Constructor),
+		functionIDs:    make(map[uint32]*Function),
+	}
+
+	var errs ErrorList
+
+	for _, prog := range progs {
+		for _, decl := range typeDecls(prog) {
+			s.declare(decl, &errs)
+		}
+
+		for _, decl := range prog.Functions {
+			d, ok := decl.(*CombDecl)
+			if !ok {
+				continue
+			}
+
+			if prev, ok := s.functions[d.Name()]; ok {
+				errs.Add(d.Pos(), fmt.Sprintf("function %s redeclared, previous declaration at %v", d.Name(), prev.Decl.Pos()))
+				continue
+			}
+
+			f := &Function{name: d.Name(), ID: combinatorID(d), Decl: d}
+			s.Functions = append(s.Functions, f)
+			s.functions[f.name] = f
+			if _, ok := s.functionIDs[f.ID]; !ok {
+				s.functionIDs[f.ID] = f
+			}
+		}
+	}
+
+	for _, prog := range progs {
+		s.resolve(prog, &errs)
+	}
+	s.buildFields()
+	s.analyzeTypes()
+	for _, prog := range progs {
+		s.instantiate(prog, &errs)
+	}
+
+	errs.SortFiles(Filenames(progs...))
+	return s, errs.Err()
+}
+
+// typeDecls returns the declarations of the constructors and types sections
+// of prog in source order.
+func typeDecls(prog *Program) []Declaration {
+	decls := append(append([]Declaration(nil), prog.Constructors...), prog.Types...)
+	sort.SliceStable(decls, func(i, j int) bool {
+		return decls[i].Pos().Offset < decls[j].Pos().Offset
+	})
+	return decls
+}
+
+// declare declares a constructor or handles a final declaration.
+func (s *Schema) declare(decl Declaration, errs *ErrorList) {
+	var typ *BoxedTypeIdent
+	switch d := decl.(type) {
+	case *CombDecl:
+		typ = d.Result.Name
+	case *BuiltinCombDecl:
+		typ = d.Result
+	case *FinalDecl:
+		s.declareFinal(d, errs)
+		return
+	default:
+		return
+	}
+
+	comb := decl.(Combinator)
+	if prev, ok := s.constructors[comb.Name()]; ok {
+		errs.Add(comb.Pos(), fmt.Sprintf("constructor %s redeclared, previous declaration at %v", comb.Name(), prev.Pos()))
+		return
+	}
+	if t, ok := s.types[typ.Name]; ok && t.final.IsValid() {
+		errs.Add(comb.Pos(), fmt.Sprintf("cannot add constructor %s to final type %s, declared final at %v",
+			comb.Name(), t.name, t.final))
+		return
+	}
+
+	c := &Constructor{name: comb.Name(), ID: combinatorID(comb), Decl: comb}
+	c.Type = s.declareType(typ)
+	c.Type.Constructors = append(c.Type.Constructors, c)
+
+	s.Constructors = append(s.Constructors, c)
+	s.constructors[c.name] = c
+	if _, ok := s.constructorIDs[c.ID]; !ok {
+		s.constructorIDs[c.ID] = c
+	}
+}
+
+var finalKeywords = map[Item]string{
+	ItemNew:   "New",
+	ItemFinal: "Final",
+	ItemEmpty: "Empty",
+}
+
+// declareFinal handles the final declarations New, Final and Empty.
+func (s *Schema) declareFinal(d *FinalDecl, errs *ErrorList) {
+	name := d.Name.Name
+
+	switch d.Kind {
+	case ItemNew, ItemEmpty:
+		if t, ok := s.Lookup(name).(*Type); ok {
+			if t.Pos().IsValid() {
+				errs.Add(d.Pos(), fmt.Sprintf("%s %s: type %s already declared at %v", finalKeywords[d.Kind], name, name, t.Pos()))
+			} else {
+				errs.Add(d.Pos(), fmt.Sprintf("%s %s: type %s is predeclared", finalKeywords[d.Kind], name, name))
+			}
+			return
+		}
+		t := s.declareType(d.Name)
+		if d.Kind == ItemEmpty {
+			// a type without constructors can't get any later on
+			t.final = d.Pos()
+		}
+	case ItemFinal:
+		t, ok := s.types[name]
+		if !ok {
+			errs.Add(d.Name.Pos(), fmt.Sprintf("undefined: %s", name))
+			return
+		}
+		if !t.final.IsValid() {
+			t.final = d.Pos()
+		}
+	}
+}
+
+// declareType returns the type named by ident, declaring it if needed.
+func (s *Schema) declareType(ident *BoxedTypeIdent) *Type {
+	if t, ok := s.types[ident.Name]; ok {
+		return t
+	}
+
+	t := &Type{name: ident.Name, pos: ident.Pos()}
+	s.Types = append(s.Types, t)
+	s.types[t.name] = t
+	return t
+}
+
+// Type returns the type with the given name, or nil.
+func (s *Schema) Type(name string) *Type { return s.types[name] }
+
+// Constructor returns the constructor with the given name, or nil.
+func (s *Schema) Constructor(name string) *Constructor { return s.constructors[name] }
+
+// Function returns the function with the given name, or nil.
+func (s *Schema) Function(name string) *Function { return s.functions[name] }
+
+// ConstructorByID returns the constructor with the given combinator-name, or
+// nil. If several constructors share the id, the first one is returned.
+func (s *Schema) ConstructorByID(id uint32) *Constructor { return s.constructorIDs[id] }
+
+// FunctionByID returns the function with the given combinator-name, or nil.
+// If several functions share the id, the first one is returned.
+func (s *Schema) FunctionByID(id uint32) *Function { return s.functionIDs[id] }
+
+// Name returns the name of the function.
+func (f *Function) Name() string { return f.name }
+
+// TypeID returns the type number of the type with the given name. ok is false
+// if there is no such type.
+func (s *Schema) TypeID(name string) (id uint32, ok bool) {
+	t := s.types[name]
+	if t == nil {
+		return 0, false
+	}
+	return t.ID(), true
+}
+
+// ID returns the type number, the sum of the combinator-names of the type's
+// constructors.
+func (t *Type) ID() uint32 {
+	var id uint32
+	for _, c := range t.Constructors {
+		id += c.ID
+	}
+	return id
+}
+
+// combinatorID returns the explicit combinator-name of comb, or the computed
+// one if there is none.
+func combinatorID(comb Combinator) uint32 {
+	var id *FullCombinatorId
+	switch d := comb.(type) {
+	case *CombDecl:
+		id = d.Id
+	case *BuiltinCombDecl:
+		id = d.Id
+	}
+
+	if v, ok := id.ID(); ok {
+		return v
+	}
+	return comb.ComputedID()
+}
@@ -0,0 +1,93 @@
+package tl
+
+import (
+	"os"
+	"testing"
+)
+
+func TestSchema_TypeID(t *testing.T) {
+	const src = `
+int ? = Int;
+boolFalse#bc799737 = Bool;
+boolTrue#997275b5 = Bool;
+user#d23c81a3 id:int first_name:string last_name:string = User;
+userEmpty id:int = User;
+---functions---
+getUser#b0f732d5 int = User;
+`
+
+	schema, err := NewSchema(parseString(t, src))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var tests = []struct {
+		name string
+		id   uint32
+		ok   bool
+	}{
+		{"Int", 0xa8509bda, true},
+		{"Bool", (0xbc799737 + 0x997275b5) & 0xffffffff, true},
+		{"User", 0xd23c81a3 + computeCRC32("userEmpty id:int = User"), true},
+		{"getUser", 0, false},
+		{"Vector", 0, false},
+	}
+
+	for _, tt := range tests {
+		id, ok := schema.TypeID(tt.name)
+		if id != tt.id || ok != tt.ok {
+			t.Errorf("TypeID(%q) = %08x, %v; expected %08x, %v", tt.name, id, ok, tt.id, tt.ok)
+		}
+	}
+
+	if typ := schema.Type("User"); len(typ.Constructors) != 2 || typ.Constructors[1] != schema.Constructor("userEmpty") {
+		t.Errorf("bad constructors of User: %v", typ.Constructors)
+	}
+	if f := schema.Function("getUser"); f == nil || f.ID != 0xb0f732d5 {
+		t.Errorf("bad function getUser: %v", f)
+	}
+}
+
+func TestSchema_TypeID_schema(t *testing.T) {
+	f, err := os.Open("schema.tl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	parser := NewParser(f)
+	program := parser.Parse()
+	if parser.Err() != nil {
+		t.Fatal(parser.Err())
+	}
+
+	schema, err := NewSchema(program)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const want = 0x7f3b18ea + 0x7da07ec9 + 0x1023dbe8 + 0x9b447325 + 0x179be863
+	if id, _ := schema.TypeID("InputPeer"); id != want&0xffffffff {
+		t.Errorf("bad type number of InputPeer: got %08x, expected %08x", id, want&0xffffffff)
+	}
+}
+
+func TestNewSchema_redeclared(t *testing.T) {
+	const src = `
+user id:int = User;
+user id:long = User;
+---functions---
+ping = User;
+ping = User;
+`
+
+	_, err := NewSchema(parseString(t, src))
+
+	errs, ok := err.(ErrorList)
+	if !ok || len(errs) != 2 {
+		t.Fatalf("expected 2 errors, got %v", err)
+	}
+	if errs[0].Pos.Line != 3 || errs[1].Pos.Line != 6 {
+		t.Errorf("bad error positions: %v", errs)
+	}
+}
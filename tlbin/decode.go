@@ -0,0 +1,340 @@
+package tlbin
+
+import (
+	"fmt"
+
+	"github.com/igungor/tl"
+)
+
+// A DecodeError describes malformed input: an unknown combinator-name,
+// truncated input or bad padding.
+type DecodeError struct {
+	Offset int    // offset of the malformed value in the input
+	Path   string // path of the value, e.g. user.first_name
+	Msg    string
+}
+
+func (e *DecodeError) Error() string {
+	if e.Path == "" {
+		return fmt.Sprintf("tlbin: offset %d: %s", e.Offset, e.Msg)
+	}
+	return fmt.Sprintf("tlbin: offset %d: %s: %s", e.Offset, e.Path, e.Msg)
+}
+
+// Unmarshal decodes the boxed serialization of a constructor or a function
+// call. The combinator is looked up by its combinator-name, constructors
+// first.
+//
+// Values of unbound type variables, e.g. the elements of a vector decoded on
+// its own, are decoded as boxed objects. Use UnmarshalResult to decode the
+// result of a function with its declared type.
+func Unmarshal(s *tl.Schema, data []byte) (*Object, error) {
+	d := &decodeState{s: s, data: data}
+	v, err := d.object("")
+	if err != nil {
+		return nil, err
+	}
+	if err := d.end(); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// UnmarshalResult decodes the result of the function f, e.g. the Vector<User>
+// of users.getUsers.
+func UnmarshalResult(s *tl.Schema, f *tl.Function, data []byte) (Value, error) {
+	d := &decodeState{s: s, data: data}
+	v, err := d.value(f.Name(), f.Result, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := d.end(); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// decodeState decodes a single value.
+type decodeState struct {
+	s    *tl.Schema
+	data []byte
+	off  int // offset of the next byte
+}
+
+func (d *decodeState) errorf(off int, path, format string, args ...interface{}) error {
+	return &DecodeError{Offset: off, Path: path, Msg: fmt.Sprintf(format, args...)}
+}
+
+// end reports an error if the input is not consumed.
+func (d *decodeState) end() error {
+	if n := len(d.data) - d.off; n > 0 {
+		return d.errorf(d.off, "", "%d trailing bytes", n)
+	}
+	return nil
+}
+
+// object decodes a boxed constructor or function call.
+func (d *decodeState) object(path string) (*Object, error) {
+	off := d.off
+	id, err := d.word(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if c := d.s.ConstructorByID(id); c != nil {
+		v, err := d.bare(objectPath(path, c.Name()), c, nil)
+		if err != nil {
+			return nil, err
+		}
+		if o, ok := v.(*Object); ok {
+			return o, nil
+		}
+		return nil, d.errorf(off, path, "unexpected %s", c.Name())
+	}
+	if f := d.s.FunctionByID(id); f != nil {
+		fields, err := d.fields(objectPath(path, f.Name()), f.Fields, nil, nil)
+		if err != nil {
+			return nil, err
+		}
+		return &Object{Function: f, Fields: fields}, nil
+	}
+	return nil, d.errorf(off, path, "unknown combinator-name #%08x", id)
+}
+
+// value decodes a value of the type ref.
+func (d *decodeState) value(path string, ref *tl.TypeRef, env env) (Value, error) {
+	ref = subst(ref, env)
+
+	switch obj := ref.Object.(type) {
+	case *tl.Builtin:
+		if obj == tl.Nat {
+			n, err := d.word(path)
+			return Int(n), err
+		}
+
+	case *tl.Type:
+		if ref.Boxing != tl.Boxed {
+			if len(obj.Constructors) != 1 {
+				return nil, d.errorf(d.off, path, "cannot decode bare %s: type has %d constructors", obj.Name(), len(obj.Constructors))
+			}
+			return d.bare(path, obj.Constructors[0], ref.Args)
+		}
+		off := d.off
+		id, err := d.word(path)
+		if err != nil {
+			return nil, err
+		}
+		c := d.s.ConstructorByID(id)
+		if c == nil {
+			return nil, d.errorf(off, path, "unknown constructor id #%08x of %s", id, obj.Name())
+		}
+		if c.Type != obj {
+			return nil, d.errorf(off, path, "%s is not a constructor of %s", c.Name(), obj.Name())
+		}
+		return d.bare(path, c, ref.Args)
+
+	case *tl.Constructor:
+		return d.bare(path, obj, ref.Args)
+
+	case *tl.TypeVar:
+		// unbound type variables, e.g. the X of query:!X
+		return d.object(path)
+	}
+	return nil, d.errorf(d.off, path, "cannot decode type %s", ref)
+}
+
+// bare decodes a bare value of the constructor c applied to args.
+func (d *decodeState) bare(path string, c *tl.Constructor, args []*tl.TypeRef) (Value, error) {
+	if isPrimitive(c) {
+		return d.primitive(path, c.Name())
+	}
+
+	switch c.Type.Name() {
+	case "Bool":
+		if !serialized(c.Fields) {
+			return Bool(c.Name() == "boolTrue"), nil
+		}
+	case "True":
+		if !serialized(c.Fields) {
+			return Bool(true), nil
+		}
+	}
+
+	fields, err := d.fields(path, c.Fields, bind(d.s, c, args), nil)
+	if err != nil {
+		return nil, err
+	}
+	// the elements of constructors with a single repetition, e.g. vector
+	if i := repetition(c.Fields); i >= 0 {
+		return fields[fieldKey(c.Fields[i], i)], nil
+	}
+	return &Object{Constructor: c, Fields: fields}, nil
+}
+
+// fields decodes the serialized fields of a combinator, see
+// encodeState.fields.
+func (d *decodeState) fields(path string, fields []*tl.Field, env env, nats map[*tl.Field]uint32) (map[string]Value, error) {
+	values := make(map[string]Value)
+	if nats == nil {
+		nats = make(map[*tl.Field]uint32) // values of the # fields
+	}
+	var last *tl.Field // last # field, the implicit multiplicity
+
+	for i, f := range fields {
+		if f.Optional {
+			continue
+		}
+		key := fieldKey(f, i)
+		fpath := path + "." + key
+
+		if f.Cond != nil {
+			if f.Cond.Field == nil {
+				return nil, d.errorf(d.off, fpath, "undefined flags field")
+			}
+			if !isSet(f.Cond, nats[f.Cond.Field]) {
+				continue
+			}
+		}
+
+		switch {
+		case isNat(f):
+			n, err := d.word(fpath)
+			if err != nil {
+				return nil, err
+			}
+			nats[f] = n
+			last = f
+			values[key] = Int(n)
+
+		case f.Type.Fields != nil:
+			n, ok := mult(f.Type, fields[:i], nats, last, env)
+			if !ok {
+				return nil, d.errorf(d.off, fpath, "cannot compute multiplicity")
+			}
+			// every element takes at least a word, unless all its fields
+			// are conditional; a byte is required for those, so that a
+			// hostile count can't force a huge allocation
+			size := int64(1)
+			if unconditional(f.Type.Fields) {
+				size = 4
+			}
+			if int64(n)*size > int64(len(d.data)-d.off) {
+				return nil, d.errorf(d.off, fpath, "unexpected end of input: %d elements", n)
+			}
+			elems := make(Vector, n)
+			for j := range elems {
+				v, err := d.element(fmt.Sprintf("%s[%d]", fpath, j), f.Type.Fields, env, nats)
+				if err != nil {
+					return nil, err
+				}
+				elems[j] = v
+			}
+			values[key] = elems
+
+		default:
+			v, err := d.value(fpath, f.Type, env)
+			if err != nil {
+				return nil, err
+			}
+			values[key] = v
+		}
+	}
+	return values, nil
+}
+
+// unconditional reports whether fields has a serialized field which is not
+// conditional.
+func unconditional(fields []*tl.Field) bool {
+	for _, f := range fields {
+		if !f.Optional && f.Cond == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// element decodes an element of a repetition, see encodeState.element.
+func (d *decodeState) element(path string, fields []*tl.Field, env env, nats map[*tl.Field]uint32) (Value, error) {
+	if len(fields) == 1 {
+		if f := fields[0]; f.Cond == nil && f.Type.Fields == nil {
+			return d.value(path, f.Type, env)
+		}
+	}
+	values, err := d.fields(path, fields, env, nats)
+	if err != nil {
+		return nil, err
+	}
+	return &Object{Fields: values}, nil
+}
+
+// primitive decodes a value of a builtin type.
+func (d *decodeState) primitive(path, name string) (Value, error) {
+	var (
+		v   Value
+		n   int
+		err error
+	)
+	b := d.data[d.off:]
+	switch name {
+	case "int":
+		var x int32
+		x, n, err = DecodeInt(b)
+		v = Int(x)
+	case "long":
+		var x int64
+		x, n, err = DecodeLong(b)
+		v = Long(x)
+	case "double":
+		var x float64
+		x, n, err = DecodeDouble(b)
+		v = Double(x)
+	case "string":
+		var x string
+		x, n, err = DecodeString(b)
+		v = String(x)
+	case "bytes":
+		var x []byte
+		x, n, err = DecodeBytes(b)
+		v = Bytes(x)
+	case "int128":
+		var x [16]byte
+		x, n, err = DecodeInt128(b)
+		v = Int128(x)
+	case "int256":
+		var x [32]byte
+		x, n, err = DecodeInt256(b)
+		v = Int256(x)
+	default:
+		return nil, d.errorf(d.off, path, "cannot decode builtin %s", name)
+	}
+	if err != nil {
+		return nil, d.error(path, err)
+	}
+	d.off += n
+	return v, nil
+}
+
+// word decodes a combinator-name or a value of #.
+func (d *decodeState) word(path string) (uint32, error) {
+	v, n, err := DecodeInt(d.data[d.off:])
+	if err != nil {
+		return 0, d.error(path, err)
+	}
+	d.off += n
+	return uint32(v), nil
+}
+
+// error positions the error of a primitive decoded at the current offset.
+func (d *decodeState) error(path string, err error) error {
+	e := err.(*DecodeError)
+	return &DecodeError{Offset: d.off + e.Offset, Path: path, Msg: e.Msg}
+}
+
+// objectPath returns the path of an object of the combinator name decoded at
+// path: the combinator name at the top level, path otherwise.
+func objectPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path
+}
@@ -0,0 +1,169 @@
+package tlbin
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestUnmarshalResult(t *testing.T) {
+	s := parseSchema(t, testSchema)
+
+	// the response of getUsers([2, 3, 4]) in NOTES.md
+	data := words(0x1cb5c415, 0x3, 0xd23c81a3, 0x2, 0x74655005, 0x00007265, 0x72615006, 0x72656b,
+		0xc67599d1, 0x3, 0xd23c81a3, 0x4, 0x686f4a04, 0x6e, 0x656f4403)
+
+	got, err := UnmarshalResult(s, s.Function("getUsers"), data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := Vector{
+		obj(t, s, "user", map[string]Value{"id": Int(2), "first_name": String("Peter"), "last_name": String("Parker")}),
+		obj(t, s, "userEmpty", map[string]Value{"id": Int(3)}),
+		obj(t, s, "user", map[string]Value{"id": Int(4), "first_name": String("John"), "last_name": String("Doe")}),
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %#v, expected %#v", got, want)
+	}
+}
+
+// TestUnmarshal decodes the serializations of TestMarshal and encodes the
+// decoded values again.
+func TestUnmarshal(t *testing.T) {
+	s := parseSchema(t, testSchema)
+
+	var tests = []struct {
+		data []byte
+		want *Object
+	}{
+		{
+			words(0x2d84d5f5, 0x1cb5c415, 3, 2, 3, 4),
+			obj(t, s, "getUsers", map[string]Value{"_0": Vector{Int(2), Int(3), Int(4)}}),
+		},
+		{
+			words(1, 3, 5, 0x00696802),
+			obj(t, s, "message", map[string]Value{"flags": Int(3), "out": Bool(true), "id": Int(5), "text": String("hi")}),
+		},
+		{
+			cat(words(2, 0x03020103), make([]byte, 16), words(0xbc799737, 0, 0x3fe00000)),
+			obj(t, s, "blob", map[string]Value{"data": Bytes{1, 2, 3}, "nonce": Int128{}, "ok": Bool(false), "ratio": Double(0.5)}),
+		},
+		{
+			words(3, 2, 1, 2, 0, 3, 0xffffffff, 0xffffffff),
+			obj(t, s, "matrix", map[string]Value{"n": Int(2), "rows": Vector{
+				&Object{Fields: map[string]Value{"a": Int(1), "b": Long(2)}},
+				&Object{Fields: map[string]Value{"a": Int(3), "b": Long(-1)}},
+			}}),
+		},
+		{
+			words(4, 1, 7, 0, 0x1cb5c415, 1, 0xc67599d1, 3),
+			obj(t, s, "pair", map[string]Value{
+				"ids":   Vector{Long(7)},
+				"users": Vector{obj(t, s, "userEmpty", map[string]Value{"id": Int(3)})},
+			}),
+		},
+		{
+			words(0xda9b0d0d, 23, 0x2d84d5f5, 0x1cb5c415, 0),
+			obj(t, s, "invokeWithLayer", map[string]Value{
+				"layer": Int(23),
+				"query": obj(t, s, "getUsers", map[string]Value{"_0": Vector{}}),
+			}),
+		},
+		{
+			words(5, 8, 0xbc799737, 0),
+			obj(t, s, "msg", map[string]Value{"flags": Int(8), "big": Bool(false), "data": String("")}),
+		},
+		{
+			words(7, 1, 2, 1, 2, 3, 4),
+			obj(t, s, "grid", map[string]Value{"flags": Int(1), "n": Int(2), "rows": Vector{
+				&Object{Fields: map[string]Value{"a": Int(1), "b": Int(2)}},
+				&Object{Fields: map[string]Value{"a": Int(3), "b": Int(4)}},
+			}}),
+		},
+		{
+			words(7, 0, 1, 1),
+			obj(t, s, "grid", map[string]Value{"flags": Int(0), "n": Int(1), "rows": Vector{
+				&Object{Fields: map[string]Value{"a": Int(1)}},
+			}}),
+		},
+	}
+
+	for _, tt := range tests {
+		got, err := Unmarshal(s, tt.data)
+		if err != nil {
+			t.Errorf("%s: %v", tt.want.Name(), err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s:\ngot  %#v\nwant %#v", tt.want.Name(), got, tt.want)
+			continue
+		}
+
+		data, err := Marshal(s, got)
+		if err != nil {
+			t.Errorf("%s: encoding decoded value: %v", tt.want.Name(), err)
+			continue
+		}
+		if !bytes.Equal(data, tt.data) {
+			t.Errorf("%s: round trip:\ngot  % x\nwant % x", tt.want.Name(), data, tt.data)
+		}
+	}
+}
+
+func TestUnmarshalErrors(t *testing.T) {
+	s := parseSchema(t, testSchema)
+
+	var tests = []struct {
+		data []byte
+		err  string
+	}{
+		{
+			words(0xdeadbeef),
+			"tlbin: offset 0: unknown combinator-name #deadbeef",
+		},
+		{
+			words(0xd23c81a3, 2, 0x74655005),
+			"tlbin: offset 8: user.first_name: unexpected end of input: string of 5 bytes",
+		},
+		{
+			words(0xd23c81a3, 2, 0x74655005, 0x01007265, 0x00000000),
+			"tlbin: offset 15: user.first_name: bad padding",
+		},
+		{
+			words(0xc67599d1, 3, 0),
+			"tlbin: offset 8: 4 trailing bytes",
+		},
+		{
+			words(4, 0, 0x1cb5c415, 1, 1),
+			"tlbin: offset 16: pair.users._2[0]: message is not a constructor of User",
+		},
+		{
+			words(4, 0, 0x1cb5c415, 1, 0xd23c81a4),
+			"tlbin: offset 16: pair.users._2[0]: unknown constructor id #d23c81a4 of User",
+		},
+		{
+			words(0x2d84d5f5, 0x1cb5c415, 0x7fffffff),
+			"tlbin: offset 12: getUsers._0._2: unexpected end of input: 2147483647 elements",
+		},
+		{
+			words(1, 1, 5),
+			"tlbin: offset 12: message.text: unexpected end of input",
+		},
+		{
+			words(8, 0, 0x7fffffff),
+			"tlbin: offset 12: holes.xs: unexpected end of input: 2147483647 elements",
+		},
+	}
+
+	for _, tt := range tests {
+		_, err := Unmarshal(s, tt.data)
+		if err == nil {
+			t.Errorf("% x: expected error %q", tt.data, tt.err)
+			continue
+		}
+		if err.Error() != tt.err {
+			t.Errorf("% x: got error %q, expected %q", tt.data, err, tt.err)
+		}
+	}
+}
@@ -0,0 +1,620 @@
+// Package tlbin implements the binary serialization of TL values, driven by
+// a resolved schema rather than by generated code.
+//
+// Values are serialized as sequences of little-endian 32-bit words. A boxed
+// value starts with the combinator-name of its constructor, a bare value
+// doesn't. Vectors are serialized as the vector constructor followed by the
+// number of elements and the elements, e.g. `getUsers([2, 3, 4])` as
+//
+//   0x2d84d5f5 0x1cb5c415 0x3 0x2 0x3 0x4
+//
+// Conditional fields are serialized only if their bit of the flags field is
+// set.
+package tlbin
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/igungor/tl"
+)
+
+// An EncodeError describes a value which cannot be encoded.
+type EncodeError struct {
+	Path string // path of the value, e.g. getUsers._0[1]
+	Msg  string
+}
+
+func (e *EncodeError) Error() string {
+	if e.Path == "" {
+		return "tlbin: " + e.Msg
+	}
+	return "tlbin: " + e.Path + ": " + e.Msg
+}
+
+// An Encoder writes TL values to an output stream.
+type Encoder struct {
+	w io.Writer
+	s *tl.Schema
+}
+
+// NewEncoder returns a new encoder that writes to w the values of the
+// combinators of s.
+func NewEncoder(w io.Writer, s *tl.Schema) *Encoder {
+	return &Encoder{w: w, s: s}
+}
+
+// Encode writes the boxed serialization of v to the stream: the
+// combinator-name of its function or constructor followed by its fields.
+func (enc *Encoder) Encode(v *Object) error {
+	e := &encodeState{s: enc.s}
+	if err := e.object(v.Name(), v); err != nil {
+		return err
+	}
+	_, err := enc.w.Write(e.buf)
+	return err
+}
+
+// Marshal returns the boxed serialization of v.
+func Marshal(s *tl.Schema, v *Object) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := NewEncoder(&buf, s).Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// MarshalResult returns the serialization of v as a result of the function
+// f, e.g. the Vector<User> of users.getUsers.
+func MarshalResult(s *tl.Schema, f *tl.Function, v Value) ([]byte, error) {
+	e := &encodeState{s: s}
+	if err := e.value(f.Name(), f.Result, nil, v); err != nil {
+		return nil, err
+	}
+	return e.buf, nil
+}
+
+// env binds the type variables of a combinator to the arguments of the type
+// it is used as, e.g. the t of vector to int in Vector<int>.
+type env map[tl.Object]*tl.TypeRef
+
+// encodeState encodes a single value.
+type encodeState struct {
+	s   *tl.Schema
+	buf []byte
+}
+
+func (e *encodeState) errorf(path, format string, args ...interface{}) error {
+	return &EncodeError{Path: path, Msg: fmt.Sprintf(format, args...)}
+}
+
+// object encodes a boxed function call or constructor.
+func (e *encodeState) object(path string, v *Object) error {
+	switch {
+	case v.Function != nil:
+		e.word(v.Function.ID)
+		return e.fields(path, v.Function.Fields, nil, nil, v.Fields)
+	case v.Constructor != nil:
+		e.word(v.Constructor.ID)
+		return e.bare(path, v.Constructor, nil, v)
+	}
+	return e.errorf(path, "object has no constructor or function")
+}
+
+// value encodes v as a value of the type ref.
+func (e *encodeState) value(path string, ref *tl.TypeRef, env env, v Value) error {
+	ref = subst(ref, env)
+
+	switch obj := ref.Object.(type) {
+	case *tl.Builtin:
+		if obj != tl.Nat {
+			break
+		}
+		n, ok := v.(Int)
+		if !ok {
+			return e.errorf(path, "cannot use %s as #", kind(v))
+		}
+		e.word(uint32(n))
+		return nil
+
+	case *tl.Type:
+		if ref.Boxing != tl.Boxed {
+			if len(obj.Constructors) != 1 {
+				return e.errorf(path, "cannot use bare %s: type has %d constructors", obj.Name(), len(obj.Constructors))
+			}
+			return e.bare(path, obj.Constructors[0], ref.Args, v)
+		}
+		c, err := e.constructorOf(path, obj, v)
+		if err != nil {
+			return err
+		}
+		e.word(c.ID)
+		return e.bare(path, c, ref.Args, v)
+
+	case *tl.Constructor:
+		return e.bare(path, obj, ref.Args, v)
+
+	case *tl.TypeVar:
+		// unbound type variables, e.g. the X of query:!X, accept any
+		// boxed object
+		if o, ok := v.(*Object); ok {
+			return e.object(path, o)
+		}
+		return e.errorf(path, "cannot infer the type of %s for %s", obj.Name(), kind(v))
+	}
+	return e.errorf(path, "cannot encode type %s", ref)
+}
+
+// constructorOf returns the constructor of the boxed type t of the value v.
+func (e *encodeState) constructorOf(path string, t *tl.Type, v Value) (*tl.Constructor, error) {
+	switch v := v.(type) {
+	case *Object:
+		c := v.Constructor
+		if c == nil || c.Type.Name() != t.Name() {
+			return nil, e.errorf(path, "%s is not a constructor of %s", kind(v), t.Name())
+		}
+		return c, nil
+	case Bool:
+		if t.Name() == "Bool" {
+			name := "boolFalse"
+			if v {
+				name = "boolTrue"
+			}
+			if c := e.s.Constructor(name); c != nil && c.Type == t {
+				return c, nil
+			}
+		}
+	}
+
+	// values of types with a single constructor, e.g. Vector t, may omit it
+	if len(t.Constructors) == 1 {
+		return t.Constructors[0], nil
+	}
+	return nil, e.errorf(path, "cannot use %s as %s", kind(v), t.Name())
+}
+
+// bare encodes v as a bare value of the constructor c applied to args.
+func (e *encodeState) bare(path string, c *tl.Constructor, args []*tl.TypeRef, v Value) error {
+	if isPrimitive(c) {
+		return e.primitive(path, c.Name(), v)
+	}
+
+	var values map[string]Value
+	switch x := v.(type) {
+	case *Object:
+		if x.Name() != c.Name() {
+			return e.errorf(path, "cannot use %s as %s", kind(x), c.Name())
+		}
+		values = x.Fields
+	case Vector:
+		// the elements of constructors with a single repetition, e.g.
+		// vector
+		i := repetition(c.Fields)
+		if i < 0 {
+			return e.errorf(path, "cannot use Vector as %s", c.Name())
+		}
+		values = map[string]Value{fieldKey(c.Fields[i], i): x}
+	default:
+		if _, isBool := v.(Bool); v != nil && !isBool || serialized(c.Fields) {
+			return e.errorf(path, "cannot use %s as %s", kind(v), c.Name())
+		}
+		// constructors without fields, e.g. true
+	}
+
+	return e.fields(path, c.Fields, bind(e.s, c, args), nil, values)
+}
+
+// bind binds the parameters of the constructor c to the arguments of the
+// type it is used as.
+func bind(s *tl.Schema, c *tl.Constructor, args []*tl.TypeRef) env {
+	env := make(env)
+	d, ok := c.Decl.(*tl.CombDecl)
+	if !ok {
+		return env
+	}
+	for i, arg := range args {
+		if i >= len(d.Result.Args) {
+			break
+		}
+		if x, ok := d.Result.Args[i].(*tl.Var); ok {
+			if obj := s.ObjectOf(x); obj != nil {
+				env[obj] = arg
+			}
+		}
+	}
+	return env
+}
+
+// fields encodes the serialized fields of a combinator, or of an element of
+// a repetition whose conditions may refer to the # fields of the enclosing
+// fields with the values nats. nats is nil for combinators.
+func (e *encodeState) fields(path string, fields []*tl.Field, env env, nats map[*tl.Field]uint32, values map[string]Value) error {
+	if nats == nil {
+		nats = make(map[*tl.Field]uint32) // values of the # fields
+	}
+	var last *tl.Field // last # field, the implicit multiplicity
+
+	for i, f := range fields {
+		if f.Optional {
+			continue
+		}
+		key := fieldKey(f, i)
+		fpath := path + "." + key
+		v, ok := values[key]
+
+		if f.Cond != nil {
+			if f.Cond.Field == nil {
+				return e.errorf(fpath, "undefined flags field")
+			}
+			if !isSet(f.Cond, nats[f.Cond.Field]) {
+				if present(f, v, ok) {
+					return e.errorf(fpath, "field is present but %s is not set", condString(f.Cond))
+				}
+				continue
+			}
+		}
+
+		switch {
+		case isNat(f):
+			var n uint32
+			if ok {
+				x, isInt := v.(Int)
+				if !isInt {
+					return e.errorf(fpath, "cannot use %s as #", kind(v))
+				}
+				n = uint32(x)
+			} else if n, ok = inferNat(fields, i, values); !ok {
+				return e.errorf(fpath, "missing field")
+			}
+			nats[f] = n
+			last = f
+			e.word(n)
+
+		case f.Type.Fields != nil:
+			n, ok := mult(f.Type, fields[:i], nats, last, env)
+			if !ok {
+				return e.errorf(fpath, "cannot compute multiplicity")
+			}
+			elems, isVector := v.(Vector)
+			if !isVector {
+				return e.errorf(fpath, "cannot use %s as repetition", kind(v))
+			}
+			if uint32(len(elems)) != n {
+				return e.errorf(fpath, "got %d elements, expected %d", len(elems), n)
+			}
+			for j, elem := range elems {
+				if err := e.element(fmt.Sprintf("%s[%d]", fpath, j), f.Type.Fields, env, nats, elem); err != nil {
+					return err
+				}
+			}
+
+		default:
+			if !ok {
+				return e.errorf(fpath, "missing field")
+			}
+			if err := e.value(fpath, f.Type, env, v); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// element encodes an element of a repetition. The elements of repetitions
+// with a single field are the values of the field, others are objects.
+func (e *encodeState) element(path string, fields []*tl.Field, env env, nats map[*tl.Field]uint32, v Value) error {
+	if len(fields) == 1 {
+		if f := fields[0]; f.Cond == nil && f.Type.Fields == nil {
+			return e.value(path, f.Type, env, v)
+		}
+		v = &Object{Fields: map[string]Value{fieldKey(fields[0], 0): v}}
+	}
+	o, ok := v.(*Object)
+	if !ok {
+		return e.errorf(path, "cannot use %s as repetition element", kind(v))
+	}
+	return e.fields(path, fields, env, nats, o.Fields)
+}
+
+// mult returns the multiplicity of the repetition ref, given the previous
+// fields of the combinator and the values of its # fields, last being the
+// last one.
+func mult(ref *tl.TypeRef, prev []*tl.Field, nats map[*tl.Field]uint32, last *tl.Field, env env) (uint32, bool) {
+	if ref.Mult == nil {
+		if last == nil {
+			return 0, false
+		}
+		return nats[last], true
+	}
+	return natValue(ref.Mult, prev, nats, env)
+}
+
+// natValue returns the value of the nat expression ref: a constant, a #
+// field or a # parameter bound by the type arguments.
+func natValue(ref *tl.TypeRef, prev []*tl.Field, nats map[*tl.Field]uint32, env env) (uint32, bool) {
+	if v, ok := ref.Object.(*tl.TypeVar); ok {
+		for i := len(prev) - 1; i >= 0; i-- {
+			if f := prev[i]; f.Name == v.Name() && !f.Optional {
+				n, ok := nats[f]
+				return n, ok
+			}
+		}
+		arg, ok := env[v]
+		if !ok {
+			return 0, false
+		}
+		ref = arg
+	}
+	if x, ok := ref.Expr.(*tl.NatConst); ok {
+		n, err := strconv.ParseUint(x.Value, 10, 32)
+		return uint32(n), err == nil
+	}
+	return 0, false
+}
+
+// inferNat computes the value of the omitted # field i from the conditional
+// fields present, including the ones of the elements of repetitions, and the
+// lengths of the repetitions using it.
+func inferNat(fields []*tl.Field, i int, values map[string]Value) (uint32, bool) {
+	nat := fields[i]
+	var n uint32
+	found := false
+	for j := i + 1; j < len(fields); j++ {
+		f := fields[j]
+		if f.Cond != nil && f.Cond.Field == nat {
+			found = true
+			if v, ok := values[fieldKey(f, j)]; present(f, v, ok) && f.Cond.Bit >= 0 {
+				n |= 1 << uint(f.Cond.Bit)
+			}
+		}
+		if f.Type.Fields != nil {
+			elems, ok := values[fieldKey(f, j)].(Vector)
+			if multOf(f.Type, fields[:j]) == nat {
+				if !ok {
+					return 0, false
+				}
+				return uint32(len(elems)), true
+			}
+			if condOn(nat, f.Type.Fields) {
+				n |= elementBits(nat, f.Type.Fields, elems)
+				found = true
+			}
+		}
+	}
+	return n, found
+}
+
+// elementBits returns the bits of nat set by the conditional fields present
+// in the elements elems of a repetition of fields.
+func elementBits(nat *tl.Field, fields []*tl.Field, elems Vector) uint32 {
+	var n uint32
+	for i, f := range fields {
+		for _, elem := range elems {
+			v, ok := elementField(fields, i, elem)
+			if f.Cond != nil && f.Cond.Field == nat && f.Cond.Bit >= 0 && present(f, v, ok) {
+				n |= 1 << uint(f.Cond.Bit)
+			}
+			if inner, isVector := v.(Vector); f.Type.Fields != nil && isVector {
+				n |= elementBits(nat, f.Type.Fields, inner)
+			}
+		}
+	}
+	return n
+}
+
+// condOn reports whether any of fields, including the fields of their
+// repetitions, is conditional on nat.
+func condOn(nat *tl.Field, fields []*tl.Field) bool {
+	for _, f := range fields {
+		if f.Cond != nil && f.Cond.Field == nat {
+			return true
+		}
+		if f.Type.Fields != nil && condOn(nat, f.Type.Fields) {
+			return true
+		}
+	}
+	return false
+}
+
+// elementField returns the value of the i-th of fields in an element of a
+// repetition, see element.
+func elementField(fields []*tl.Field, i int, elem Value) (Value, bool) {
+	if len(fields) == 1 {
+		return elem, elem != nil
+	}
+	o, ok := elem.(*Object)
+	if !ok || o == nil {
+		return nil, false
+	}
+	v, ok := o.Fields[fieldKey(fields[i], i)]
+	return v, ok
+}
+
+// multOf returns the # field holding the multiplicity of the repetition ref,
+// or nil.
+func multOf(ref *tl.TypeRef, prev []*tl.Field) *tl.Field {
+	for i := len(prev) - 1; i >= 0; i-- {
+		f := prev[i]
+		if f.Optional || !isNat(f) {
+			continue
+		}
+		if ref.Mult == nil {
+			return f
+		}
+		if v, ok := ref.Mult.Object.(*tl.TypeVar); ok && v.Name() == f.Name {
+			return f
+		}
+	}
+	return nil
+}
+
+// primitive encodes a value of a builtin type. Ints may be used as longs and
+// Strings as bytes, and vice versa.
+func (e *encodeState) primitive(path, name string, v Value) error {
+	var err error
+	switch x := v.(type) {
+	case Int:
+		switch name {
+		case "int":
+			e.buf = EncodeInt(e.buf, int32(x))
+			return nil
+		case "long":
+			e.buf = EncodeLong(e.buf, int64(x))
+			return nil
+		}
+	case Long:
+		if name == "long" {
+			e.buf = EncodeLong(e.buf, int64(x))
+			return nil
+		}
+	case Double:
+		if name == "double" {
+			e.buf = EncodeDouble(e.buf, float64(x))
+			return nil
+		}
+	case String:
+		if name == "string" || name == "bytes" {
+			if e.buf, err = EncodeString(e.buf, string(x)); err != nil {
+				return e.errorf(path, "%s", err.(*EncodeError).Msg)
+			}
+			return nil
+		}
+	case Bytes:
+		if name == "string" || name == "bytes" {
+			if e.buf, err = EncodeBytes(e.buf, x); err != nil {
+				return e.errorf(path, "%s", err.(*EncodeError).Msg)
+			}
+			return nil
+		}
+	case Int128:
+		if name == "int128" {
+			e.buf = EncodeInt128(e.buf, x)
+			return nil
+		}
+	case Int256:
+		if name == "int256" {
+			e.buf = EncodeInt256(e.buf, x)
+			return nil
+		}
+	}
+	return e.errorf(path, "cannot use %s as %s", kind(v), name)
+}
+
+// word encodes a combinator-name or a value of #.
+func (e *encodeState) word(n uint32) {
+	e.buf = EncodeInt(e.buf, int32(n))
+}
+
+// isPrimitive reports whether c is serialized by the codec rather than by
+// its fields: the builtins int, long, double and string, and bytes, int128
+// and int256.
+func isPrimitive(c *tl.Constructor) bool {
+	switch c.Name() {
+	case "bytes", "int128", "int256":
+		return true
+	}
+	_, ok := c.Decl.(*tl.CombDecl)
+	return !ok
+}
+
+// subst returns ref with a type variable bound in env replaced.
+func subst(ref *tl.TypeRef, env env) *tl.TypeRef {
+	arg, ok := env[ref.Object]
+	if !ok || ref.Object == nil {
+		if len(ref.Args) == 0 {
+			return ref
+		}
+		r := *ref
+		r.Args = make([]*tl.TypeRef, len(ref.Args))
+		for i, a := range ref.Args {
+			r.Args[i] = subst(a, env)
+		}
+		return &r
+	}
+
+	r := *arg
+	if ref.Boxing == tl.BarePercent && r.Boxing == tl.Boxed {
+		r.Boxing = tl.BarePercent
+	}
+	return &r
+}
+
+// fieldKey returns the key of the field in field maps: its name, or _N for
+// the anonymous N-th field.
+func fieldKey(f *tl.Field, i int) string {
+	if f.Name == "" {
+		return "_" + strconv.Itoa(i)
+	}
+	return f.Name
+}
+
+func isNat(f *tl.Field) bool {
+	b, ok := f.Type.Object.(*tl.Builtin)
+	return ok && b == tl.Nat
+}
+
+// isSet reports whether the condition holds for the given flags.
+func isSet(c *tl.Condition, flags uint32) bool {
+	if c.Bit < 0 {
+		return flags != 0
+	}
+	return flags&(1<<uint(c.Bit)) != 0
+}
+
+func condString(c *tl.Condition) string {
+	if c.Bit < 0 {
+		return c.Field.Name
+	}
+	return c.Field.Name + "." + strconv.Itoa(c.Bit)
+}
+
+// present reports whether the conditional field f is present: it is given
+// and, for fields of type true, not false. False values of other types, e.g.
+// Bool, are present.
+func present(f *tl.Field, v Value, ok bool) bool {
+	if b, isBool := v.(Bool); isBool && isTrue(f.Type) {
+		return ok && bool(b)
+	}
+	return ok
+}
+
+// isTrue reports whether ref is the type True or its constructor true, whose
+// values are not serialized.
+func isTrue(ref *tl.TypeRef) bool {
+	switch obj := ref.Object.(type) {
+	case *tl.Type:
+		return obj.Name() == "True"
+	case *tl.Constructor:
+		return obj.Name() == "true"
+	}
+	return false
+}
+
+// repetition returns the index of the only serialized repetition of fields
+// if its multiplicity is implicit, as in vector, or -1. Such combinators are
+// represented by the elements of the repetition.
+func repetition(fields []*tl.Field) int {
+	index := -1
+	for i, f := range fields {
+		if f.Optional || isNat(f) {
+			continue
+		}
+		if f.Type.Fields == nil || f.Type.Mult != nil || index >= 0 {
+			return -1
+		}
+		index = i
+	}
+	return index
+}
+
+// serialized reports whether any of the fields is serialized.
+func serialized(fields []*tl.Field) bool {
+	for _, f := range fields {
+		if !f.Optional {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,219 @@
+package tlbin
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/igungor/tl"
+)
+
+const testSchema = `
+int ? = Int;
+long ? = Long;
+double ? = Double;
+string ? = String;
+
+bytes string = Bytes;
+int128 long long = Int128;
+
+boolFalse#bc799737 = Bool;
+boolTrue#997275b5 = Bool;
+true#3fedd339 = True;
+
+vector#1cb5c415 {t:Type} # [ t ] = Vector t;
+
+user#d23c81a3 id:int first_name:string last_name:string = User;
+userEmpty#c67599d1 id:int = User;
+
+message#1 flags:# out:flags.1?true id:int text:flags.0?string = Message;
+blob#2 data:bytes nonce:int128 ok:Bool ratio:double = Blob;
+matrix#3 n:# rows:n*[a:int b:long] = Matrix;
+pair#4 ids:%(Vector long) users:Vector<User> = Pair;
+msg#5 flags:# big:flags.3?Bool data:string = Msg;
+tagged#6 tag:string (Vector int) = Tagged;
+grid#7 flags:# n:# rows:n*[a:int b:flags.0?int] = Grid;
+holes#8 flags:# n:# xs:n*[x:flags.1?int] = Holes;
+---functions---
+getUsers#2d84d5f5 (Vector int) = Vector User;
+invokeWithLayer#da9b0d0d {X:Type} layer:int query:!X = X;
+`
+
+func parseSchema(t *testing.T, src string) *tl.Schema {
+	parser := tl.NewParser(bytes.NewBufferString(src))
+	program := parser.Parse()
+	if err := parser.Err(); err != nil {
+		t.Fatal(err)
+	}
+	s, err := tl.NewSchema(program)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+// words returns the serialization of the given 32-bit words.
+func words(w ...uint32) []byte {
+	b := make([]byte, 4*len(w))
+	for i, n := range w {
+		binary.LittleEndian.PutUint32(b[4*i:], n)
+	}
+	return b
+}
+
+func cat(list ...[]byte) []byte {
+	return bytes.Join(list, nil)
+}
+
+// obj returns the object of the named combinator of s.
+func obj(t *testing.T, s *tl.Schema, name string, fields map[string]Value) *Object {
+	o, err := NewObject(s, name, fields)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return o
+}
+
+func TestMarshal(t *testing.T) {
+	s := parseSchema(t, testSchema)
+
+	var nonce Int128
+	for i := range nonce {
+		nonce[i] = byte(i)
+	}
+
+	var tests = []struct {
+		v    *Object
+		want []byte
+	}{
+		{
+			obj(t, s, "getUsers", map[string]Value{"_0": Vector{Int(2), Int(3), Int(4)}}),
+			words(0x2d84d5f5, 0x1cb5c415, 3, 2, 3, 4),
+		},
+		{
+			obj(t, s, "user", map[string]Value{"id": Int(2), "first_name": String("Peter"), "last_name": String("Parker")}),
+			words(0xd23c81a3, 2, 0x74655005, 0x00007265, 0x72615006, 0x0072656b),
+		},
+		{
+			obj(t, s, "message", map[string]Value{"out": Bool(true), "id": Int(5)}),
+			words(1, 2, 5),
+		},
+		{
+			obj(t, s, "message", map[string]Value{"out": Bool(false), "id": Int(5), "text": String("hi")}),
+			words(1, 1, 5, 0x00696802),
+		},
+		{
+			obj(t, s, "message", map[string]Value{"flags": Int(2), "out": Bool(true), "id": Int(5)}),
+			words(1, 2, 5),
+		},
+		{
+			obj(t, s, "blob", map[string]Value{"data": Bytes{1, 2, 3}, "nonce": nonce, "ok": Bool(true), "ratio": Double(0.5)}),
+			cat(words(2, 0x03020103), nonce[:], words(0x997275b5, 0, 0x3fe00000)),
+		},
+		{
+			obj(t, s, "matrix", map[string]Value{"rows": Vector{
+				&Object{Fields: map[string]Value{"a": Int(1), "b": Long(2)}},
+				&Object{Fields: map[string]Value{"a": Int(3), "b": Long(-1)}},
+			}}),
+			words(3, 2, 1, 2, 0, 3, 0xffffffff, 0xffffffff),
+		},
+		{
+			obj(t, s, "pair", map[string]Value{
+				"ids":   Vector{Long(7)},
+				"users": Vector{obj(t, s, "userEmpty", map[string]Value{"id": Int(3)})},
+			}),
+			words(4, 1, 7, 0, 0x1cb5c415, 1, 0xc67599d1, 3),
+		},
+		{
+			obj(t, s, "invokeWithLayer", map[string]Value{
+				"layer": Int(23),
+				"query": obj(t, s, "getUsers", map[string]Value{"_0": Vector{}}),
+			}),
+			words(0xda9b0d0d, 23, 0x2d84d5f5, 0x1cb5c415, 0),
+		},
+		{
+			obj(t, s, "msg", map[string]Value{"big": Bool(false), "data": String("")}),
+			words(5, 8, 0xbc799737, 0),
+		},
+		{
+			obj(t, s, "grid", map[string]Value{"rows": Vector{
+				&Object{Fields: map[string]Value{"a": Int(1), "b": Int(2)}},
+				&Object{Fields: map[string]Value{"a": Int(3), "b": Int(4)}},
+			}}),
+			words(7, 1, 2, 1, 2, 3, 4),
+		},
+		{
+			obj(t, s, "grid", map[string]Value{"flags": Int(0), "rows": Vector{
+				&Object{Fields: map[string]Value{"a": Int(1)}},
+			}}),
+			words(7, 0, 1, 1),
+		},
+		{
+			obj(t, s, "holes", map[string]Value{"xs": Vector{Int(5), Int(6)}}),
+			words(8, 2, 2, 5, 6),
+		},
+	}
+
+	for _, tt := range tests {
+		got, err := Marshal(s, tt.v)
+		if err != nil {
+			t.Errorf("%s: %v", tt.v.Name(), err)
+			continue
+		}
+		if !bytes.Equal(got, tt.want) {
+			t.Errorf("%s:\ngot  % x\nwant % x", tt.v.Name(), got, tt.want)
+		}
+	}
+}
+
+func TestMarshalErrors(t *testing.T) {
+	s := parseSchema(t, testSchema)
+
+	var tests = []struct {
+		v   *Object
+		err string
+	}{
+		{
+			&Object{Fields: map[string]Value{"id": Int(2)}},
+			"tlbin: object has no constructor or function",
+		},
+		{
+			obj(t, s, "user", map[string]Value{"id": Int(2), "first_name": String("Peter")}),
+			"tlbin: user.last_name: missing field",
+		},
+		{
+			obj(t, s, "user", map[string]Value{"id": String("2"), "first_name": String("Peter"), "last_name": String("Parker")}),
+			"tlbin: user.id: cannot use String as int",
+		},
+		{
+			obj(t, s, "getUsers", map[string]Value{"_0": Vector{Int(2), Long(1) << 40}}),
+			"tlbin: getUsers._0._2[1]: cannot use Long as int",
+		},
+		{
+			obj(t, s, "message", map[string]Value{"flags": Int(0), "id": Int(5), "text": String("hi")}),
+			"tlbin: message.text: field is present but flags.0 is not set",
+		},
+		{
+			obj(t, s, "matrix", map[string]Value{"n": Int(3), "rows": Vector{}}),
+			"tlbin: matrix.rows: got 0 elements, expected 3",
+		},
+		{
+			obj(t, s, "pair", map[string]Value{
+				"ids":   Vector{},
+				"users": Vector{obj(t, s, "message", nil)},
+			}),
+			"tlbin: pair.users._2[0]: message is not a constructor of User",
+		},
+	}
+
+	for _, tt := range tests {
+		_, err := Marshal(s, tt.v)
+		if err == nil {
+			t.Errorf("%s: expected error %q", tt.v.Name(), tt.err)
+			continue
+		}
+		if err.Error() != tt.err {
+			t.Errorf("%s: got error %q, expected %q", tt.v.Name(), err, tt.err)
+		}
+	}
+}
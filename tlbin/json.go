@@ -0,0 +1,564 @@
+package tlbin
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+
+	"github.com/igungor/tl"
+)
+
+// The JSON form of values, written by JSON and read by FromJSON, is:
+//
+//   - objects: JSON objects with the name of their combinator in the "_"
+//     member and their fields in the others, keyed as in Object.Fields, e.g.
+//     {"_":"user","id":2,"first_name":"Peter","last_name":"Parker"}. The
+//     elements of repetitions of several fields have no "_" member.
+//   - vectors and repetitions: arrays.
+//   - int and #: numbers; double: numbers, which cannot be NaN or infinite.
+//   - long: decimal strings, as JSON numbers lose precision beyond 2^53.
+//   - string: strings, which are UTF-8 in JSON.
+//   - bytes, int128 and int256: base64 strings.
+//   - Bool and true: true or false.
+//
+// Conditional fields are omitted if not set, and so are the # fields which
+// can be inferred from the conditional fields present and the lengths of the
+// repetitions. When reading, "_" may be omitted if the type of the value
+// implies its constructor, e.g. for bare types and Vector t, and null fields
+// are omitted fields. Longs may also be given as numbers.
+//
+// The field of a combinator whose only serialized field is anonymous, keyed
+// _0 for getUsers (Vector int) = Vector User, may be given by any name, e.g.
+// {"_":"getUsers","id":[2,3,4]}. It is still written as _0. Combinators with
+// other fields reject members not naming their fields.
+//
+// Untagged JSON, written with JSONConfig.Untagged, has no "_" members, e.g.
+// [{"id":2,"first_name":"Peter","last_name":"Parker"}] for a Vector<User>.
+// It can be read back only where the types imply the constructors.
+
+// A JSONError describes JSON which is not the form of a value of the schema.
+type JSONError struct {
+	Path string // path of the value, e.g. getUsers.id[1]
+	Msg  string
+}
+
+func (e *JSONError) Error() string {
+	if e.Path == "" {
+		return "tlbin: json: " + e.Msg
+	}
+	return "tlbin: json: " + e.Path + ": " + e.Msg
+}
+
+// A JSONConfig controls the output of JSON.
+type JSONConfig struct {
+	// Untagged omits the "_" members naming the combinators of objects.
+	Untagged bool
+}
+
+// JSON returns the JSON form of v using the default config.
+func JSON(v Value) ([]byte, error) {
+	return (&JSONConfig{}).JSON(v)
+}
+
+// JSON returns the JSON form of v for the configuration cfg.
+func (cfg *JSONConfig) JSON(v Value) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := cfg.write(&buf, "", v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// write writes the JSON form of v to buf.
+func (cfg *JSONConfig) write(buf *bytes.Buffer, path string, v Value) error {
+	switch v := v.(type) {
+	case nil:
+		buf.WriteString("null")
+	case *Object:
+		path = objectPath(path, v.Name())
+		buf.WriteByte('{')
+		if name := v.Name(); name != "" && !cfg.Untagged {
+			buf.WriteString(`"_":`)
+			writeString(buf, name)
+		}
+		for _, key := range jsonKeys(v) {
+			if buf.Bytes()[buf.Len()-1] != '{' {
+				buf.WriteByte(',')
+			}
+			writeString(buf, key)
+			buf.WriteByte(':')
+			if err := cfg.write(buf, path+"."+key, v.Fields[key]); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('}')
+	case Vector:
+		buf.WriteByte('[')
+		for i, x := range v {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := cfg.write(buf, fmt.Sprintf("%s[%d]", path, i), x); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(']')
+	case Int:
+		buf.WriteString(strconv.FormatInt(int64(v), 10))
+	case Long:
+		buf.WriteString(`"` + strconv.FormatInt(int64(v), 10) + `"`)
+	case Double:
+		if math.IsNaN(float64(v)) || math.IsInf(float64(v), 0) {
+			return &JSONError{Path: path, Msg: fmt.Sprintf("cannot represent %v in JSON", float64(v))}
+		}
+		buf.WriteString(strconv.FormatFloat(float64(v), 'g', -1, 64))
+	case String:
+		writeString(buf, string(v))
+	case Bytes:
+		writeString(buf, base64.StdEncoding.EncodeToString(v))
+	case Int128:
+		writeString(buf, base64.StdEncoding.EncodeToString(v[:]))
+	case Int256:
+		writeString(buf, base64.StdEncoding.EncodeToString(v[:]))
+	case Bool:
+		buf.WriteString(strconv.FormatBool(bool(v)))
+	default:
+		return &JSONError{Path: path, Msg: fmt.Sprintf("cannot represent %T in JSON", v)}
+	}
+	return nil
+}
+
+// jsonKeys returns the keys of the fields of o written in JSON: in
+// declaration order without the inferable # fields for objects of
+// combinators, sorted otherwise.
+func jsonKeys(o *Object) []string {
+	fields := o.combFields()
+	if fields == nil {
+		keys := make([]string, 0, len(o.Fields))
+		for key := range o.Fields {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		return keys
+	}
+
+	var keys []string
+	for i, f := range fields {
+		key := fieldKey(f, i)
+		v, ok := o.Fields[key]
+		if !ok {
+			continue
+		}
+		if n, isInt := v.(Int); isInt && isNat(f) {
+			if inferred, ok := inferNat(fields, i, o.Fields); ok && inferred == uint32(n) {
+				continue
+			}
+		}
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// writeString writes s as a JSON string without escaping HTML.
+func writeString(buf *bytes.Buffer, s string) {
+	enc := json.NewEncoder(buf)
+	enc.SetEscapeHTML(false)
+	enc.Encode(s)
+	buf.Truncate(buf.Len() - 1) // trailing newline
+}
+
+// FromJSON returns the object of the JSON form data of a constructor or a
+// function call of s, e.g. {"_":"getUsers","id":[2,3,4]}.
+func FromJSON(s *tl.Schema, data []byte) (*Object, error) {
+	x, err := parseJSON(data)
+	if err != nil {
+		return nil, err
+	}
+	return (&jsonState{s: s}).object("", x)
+}
+
+// ResultFromJSON returns the value of the JSON form data of a result of the
+// function f, e.g. the Vector<User> of getUsers.
+func ResultFromJSON(s *tl.Schema, f *tl.Function, data []byte) (Value, error) {
+	x, err := parseJSON(data)
+	if err != nil {
+		return nil, err
+	}
+	return (&jsonState{s: s}).value(f.Name(), f.Result, nil, x)
+}
+
+// parseJSON parses data into generic JSON values with json.Number numbers.
+func parseJSON(data []byte) (interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var x interface{}
+	if err := dec.Decode(&x); err != nil {
+		return nil, &JSONError{Msg: err.Error()}
+	}
+	if dec.More() {
+		return nil, &JSONError{Msg: "unexpected data after top-level value"}
+	}
+	return x, nil
+}
+
+// jsonState converts generic JSON values to values of a schema.
+type jsonState struct {
+	s *tl.Schema
+}
+
+func (j *jsonState) errorf(path, format string, args ...interface{}) error {
+	return &JSONError{Path: path, Msg: fmt.Sprintf(format, args...)}
+}
+
+// object converts a boxed constructor or function call.
+func (j *jsonState) object(path string, x interface{}) (*Object, error) {
+	m, ok := x.(map[string]interface{})
+	if !ok {
+		return nil, j.errorf(path, "cannot use %s as object", jsonKind(x))
+	}
+	name, ok := m["_"].(string)
+	if !ok {
+		return nil, j.errorf(path, `missing combinator name "_"`)
+	}
+
+	if c := j.s.Constructor(name); c != nil {
+		v, err := j.bare(objectPath(path, name), c, nil, m)
+		if err != nil {
+			return nil, err
+		}
+		if o, ok := v.(*Object); ok {
+			return o, nil
+		}
+		return nil, j.errorf(path, "unexpected %s", name)
+	}
+	if f := j.s.Function(name); f != nil {
+		fields, err := j.fields(objectPath(path, name), f.Fields, nil, m)
+		if err != nil {
+			return nil, err
+		}
+		return &Object{Function: f, Fields: fields}, nil
+	}
+	return nil, j.errorf(path, "unknown combinator %s", name)
+}
+
+// value converts a value of the type ref.
+func (j *jsonState) value(path string, ref *tl.TypeRef, env env, x interface{}) (Value, error) {
+	ref = subst(ref, env)
+
+	switch obj := ref.Object.(type) {
+	case *tl.Builtin:
+		if obj != tl.Nat {
+			break
+		}
+		num, ok := x.(json.Number)
+		if !ok {
+			return nil, j.errorf(path, "cannot use %s as #", jsonKind(x))
+		}
+		n, err := strconv.ParseUint(string(num), 10, 32)
+		if err != nil {
+			return nil, j.errorf(path, "bad # %s", num)
+		}
+		return Int(n), nil
+
+	case *tl.Type:
+		if ref.Boxing != tl.Boxed {
+			if len(obj.Constructors) != 1 {
+				return nil, j.errorf(path, "cannot use bare %s: type has %d constructors", obj.Name(), len(obj.Constructors))
+			}
+			return j.bare(path, obj.Constructors[0], ref.Args, x)
+		}
+		if b, ok := x.(bool); ok && obj.Name() == "Bool" {
+			return Bool(b), nil
+		}
+		c, err := j.constructorOf(path, obj, x)
+		if err != nil {
+			return nil, err
+		}
+		return j.bare(path, c, ref.Args, x)
+
+	case *tl.Constructor:
+		return j.bare(path, obj, ref.Args, x)
+
+	case *tl.TypeVar:
+		// unbound type variables, e.g. the X of query:!X, take any boxed
+		// object
+		return j.object(path, x)
+	}
+	return nil, j.errorf(path, "cannot convert type %s", ref)
+}
+
+// constructorOf returns the constructor of the boxed type t of the JSON
+// value x: the one named by its "_" member, or the only one of t.
+func (j *jsonState) constructorOf(path string, t *tl.Type, x interface{}) (*tl.Constructor, error) {
+	if m, ok := x.(map[string]interface{}); ok {
+		if name, ok := m["_"].(string); ok {
+			c := j.s.Constructor(name)
+			if c == nil {
+				return nil, j.errorf(path, "unknown constructor %s", name)
+			}
+			if c.Type.Name() != t.Name() {
+				return nil, j.errorf(path, "%s is not a constructor of %s", name, t.Name())
+			}
+			return c, nil
+		}
+	}
+	if len(t.Constructors) == 1 {
+		return t.Constructors[0], nil
+	}
+	return nil, j.errorf(path, `missing constructor name "_" of %s`, t.Name())
+}
+
+// bare converts a bare value of the constructor c applied to args.
+func (j *jsonState) bare(path string, c *tl.Constructor, args []*tl.TypeRef, x interface{}) (Value, error) {
+	if isPrimitive(c) {
+		return j.primitive(path, c.Name(), x)
+	}
+
+	if b, ok := x.(bool); ok && !serialized(c.Fields) {
+		switch c.Type.Name() {
+		case "Bool":
+			if b != (c.Name() == "boolTrue") {
+				return nil, j.errorf(path, "cannot use %t as %s", b, c.Name())
+			}
+			return Bool(b), nil
+		case "True":
+			return Bool(b), nil
+		}
+	}
+
+	env := bind(j.s, c, args)
+	// the elements of constructors with a single repetition, e.g. vector
+	if i := repetition(c.Fields); i >= 0 {
+		if _, ok := x.([]interface{}); !ok {
+			return nil, j.errorf(path, "cannot use %s as %s", jsonKind(x), c.Name())
+		}
+		key := fieldKey(c.Fields[i], i)
+		fields, err := j.fields(path, c.Fields, env, map[string]interface{}{key: x})
+		if err != nil {
+			return nil, err
+		}
+		return fields[key], nil
+	}
+
+	m, ok := x.(map[string]interface{})
+	if !ok {
+		return nil, j.errorf(path, "cannot use %s as %s", jsonKind(x), c.Name())
+	}
+	if name, ok := m["_"]; ok && name != c.Name() {
+		return nil, j.errorf(path, "cannot use %v as %s", name, c.Name())
+	}
+	fields, err := j.fields(path, c.Fields, env, m)
+	if err != nil {
+		return nil, err
+	}
+	return &Object{Constructor: c, Fields: fields}, nil
+}
+
+// fields converts the members of m to the serialized fields of a
+// combinator. Members which are not fields are errors.
+func (j *jsonState) fields(path string, fields []*tl.Field, env env, m map[string]interface{}) (map[string]Value, error) {
+	m = renameAnonymous(fields, m)
+	values := make(map[string]Value)
+	known := map[string]bool{"_": true}
+
+	for i, f := range fields {
+		if f.Optional {
+			continue
+		}
+		key := fieldKey(f, i)
+		fpath := path + "." + key
+		known[key] = true
+
+		x, ok := m[key]
+		if !ok || x == nil {
+			continue
+		}
+
+		switch {
+		case isNat(f):
+			v, err := j.value(fpath, f.Type, env, x)
+			if err != nil {
+				return nil, err
+			}
+			values[key] = v
+
+		case f.Type.Fields != nil:
+			list, ok := x.([]interface{})
+			if !ok {
+				return nil, j.errorf(fpath, "cannot use %s as repetition", jsonKind(x))
+			}
+			elems := make(Vector, len(list))
+			for k, elem := range list {
+				v, err := j.element(fmt.Sprintf("%s[%d]", fpath, k), f.Type.Fields, env, elem)
+				if err != nil {
+					return nil, err
+				}
+				elems[k] = v
+			}
+			values[key] = elems
+
+		default:
+			v, err := j.value(fpath, f.Type, env, x)
+			if err != nil {
+				return nil, err
+			}
+			values[key] = v
+		}
+	}
+
+	var unknown []string
+	for key := range m {
+		if !known[key] {
+			unknown = append(unknown, key)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return nil, j.errorf(path, "unknown field %s", unknown[0])
+	}
+	return values, nil
+}
+
+// renameAnonymous returns m with the only member other than "_" keyed as the
+// field if the only serialized field of fields is anonymous, e.g. the id of
+// {"_":"getUsers","id":[2,3,4]}. It returns m otherwise, so that members not
+// naming fields of other combinators are still errors.
+func renameAnonymous(fields []*tl.Field, m map[string]interface{}) map[string]interface{} {
+	anon := ""
+	for i, f := range fields {
+		if f.Optional {
+			continue
+		}
+		if f.Name != "" || anon != "" {
+			return m
+		}
+		anon = fieldKey(f, i)
+	}
+	if _, ok := m[anon]; anon == "" || ok {
+		return m
+	}
+
+	name := ""
+	for key := range m {
+		if key != "_" {
+			if name != "" {
+				return m // several members
+			}
+			name = key
+		}
+	}
+	if name == "" {
+		return m
+	}
+
+	renamed := map[string]interface{}{anon: m[name]}
+	if x, ok := m["_"]; ok {
+		renamed["_"] = x
+	}
+	return renamed
+}
+
+// element converts an element of a repetition, see encodeState.element.
+func (j *jsonState) element(path string, fields []*tl.Field, env env, x interface{}) (Value, error) {
+	if len(fields) == 1 {
+		if f := fields[0]; f.Cond == nil && f.Type.Fields == nil {
+			return j.value(path, f.Type, env, x)
+		}
+	}
+	m, ok := x.(map[string]interface{})
+	if !ok {
+		return nil, j.errorf(path, "cannot use %s as repetition element", jsonKind(x))
+	}
+	values, err := j.fields(path, fields, env, m)
+	if err != nil {
+		return nil, err
+	}
+	return &Object{Fields: values}, nil
+}
+
+// primitive converts a value of a builtin type.
+func (j *jsonState) primitive(path, name string, x interface{}) (Value, error) {
+	switch x := x.(type) {
+	case json.Number:
+		switch name {
+		case "int":
+			n, err := strconv.ParseInt(string(x), 10, 32)
+			if err != nil {
+				return nil, j.errorf(path, "bad int %s", x)
+			}
+			return Int(n), nil
+		case "long":
+			return j.long(path, string(x))
+		case "double":
+			f, err := strconv.ParseFloat(string(x), 64)
+			if err != nil {
+				return nil, j.errorf(path, "bad double %s", x)
+			}
+			return Double(f), nil
+		}
+
+	case string:
+		switch name {
+		case "long":
+			return j.long(path, x)
+		case "string":
+			return String(x), nil
+		case "bytes", "int128", "int256":
+			b, err := base64.StdEncoding.DecodeString(x)
+			if err != nil {
+				return nil, j.errorf(path, "bad base64 %s: %v", name, err)
+			}
+			switch name {
+			case "bytes":
+				return Bytes(b), nil
+			case "int128":
+				var v Int128
+				if len(b) != len(v) {
+					return nil, j.errorf(path, "got %d bytes, expected %d", len(b), len(v))
+				}
+				copy(v[:], b)
+				return v, nil
+			default:
+				var v Int256
+				if len(b) != len(v) {
+					return nil, j.errorf(path, "got %d bytes, expected %d", len(b), len(v))
+				}
+				copy(v[:], b)
+				return v, nil
+			}
+		}
+	}
+	return nil, j.errorf(path, "cannot use %s as %s", jsonKind(x), name)
+}
+
+// long converts a long given as a decimal string or number.
+func (j *jsonState) long(path, s string) (Value, error) {
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return nil, j.errorf(path, "bad long %q", s)
+	}
+	return Long(n), nil
+}
+
+// jsonKind describes the generic JSON value x in errors.
+func jsonKind(x interface{}) string {
+	switch x.(type) {
+	case nil:
+		return "JSON null"
+	case bool:
+		return "JSON boolean"
+	case json.Number:
+		return "JSON number"
+	case string:
+		return "JSON string"
+	case []interface{}:
+		return "JSON array"
+	case map[string]interface{}:
+		return "JSON object"
+	}
+	return fmt.Sprintf("%T", x)
+}
@@ -0,0 +1,200 @@
+package tlbin
+
+import (
+	"bytes"
+	"math"
+	"testing"
+)
+
+// notesSchema is the schema of the RPC query example of NOTES.md.
+const notesSchema = `
+int ? = Int;
+string ? = String;
+
+vector#1cb5c415 {t:Type} # [ t ] = Vector t;
+
+user#d23c81a3 id:int first_name:string last_name:string = User;
+userEmpty#c67599d1 id:int = User;
+---functions---
+getUsers#2d84d5f5 (Vector int) = Vector User;
+`
+
+func TestJSONNotes(t *testing.T) {
+	s := parseSchema(t, notesSchema)
+	f := s.Function("getUsers")
+
+	// The query names the anonymous argument, which is written as _0.
+	const query = `{"_":"getUsers","_0":[2,3,4]}`
+	queryData := words(0x2d84d5f5, 0x1cb5c415, 3, 2, 3, 4)
+
+	v, err := FromJSON(s, []byte(`{"_":"getUsers","id":[2,3,4]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := Marshal(s, v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, queryData) {
+		t.Errorf("query:\ngot  % x\nwant % x", data, queryData)
+	}
+	if v, err = Unmarshal(s, data); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := JSON(v); err != nil || string(got) != query {
+		t.Errorf("query JSON: got %s, %v, expected %s", got, err, query)
+	}
+
+	const response = `[{"_":"user","id":2,"first_name":"Peter","last_name":"Parker"},{"_":"userEmpty","id":3},{"_":"user","id":4,"first_name":"John","last_name":"Doe"}]`
+	responseData := words(0x1cb5c415, 0x3, 0xd23c81a3, 0x2, 0x74655005, 0x00007265, 0x72615006, 0x72656b,
+		0xc67599d1, 0x3, 0xd23c81a3, 0x4, 0x686f4a04, 0x6e, 0x656f4403)
+
+	result, err := UnmarshalResult(s, f, responseData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := JSON(result); err != nil || string(got) != response {
+		t.Errorf("response JSON:\ngot  %s, %v\nwant %s", got, err, response)
+	}
+	// the response as shown in NOTES.md
+	const untagged = `[{"id":2,"first_name":"Peter","last_name":"Parker"},{"id":3},{"id":4,"first_name":"John","last_name":"Doe"}]`
+	if got, err := (&JSONConfig{Untagged: true}).JSON(result); err != nil || string(got) != untagged {
+		t.Errorf("untagged response JSON:\ngot  %s, %v\nwant %s", got, err, untagged)
+	}
+	if result, err = ResultFromJSON(s, f, []byte(response)); err != nil {
+		t.Fatal(err)
+	}
+	if data, err = MarshalResult(s, f, result); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, responseData) {
+		t.Errorf("response:\ngot  % x\nwant % x", data, responseData)
+	}
+}
+
+// TestJSON converts JSON to the serializations of TestMarshal and back.
+func TestJSON(t *testing.T) {
+	s := parseSchema(t, testSchema)
+
+	var tests = []struct {
+		in   string
+		data []byte
+		out  string // JSON of the decoded value, if not in
+	}{
+		{
+			in:   `{"_":"message","out":true,"id":5}`,
+			data: words(1, 2, 5),
+		},
+		{
+			in:   `{"_":"message","id":5,"text":"hi","out":null}`,
+			data: words(1, 1, 5, 0x00696802),
+			out:  `{"_":"message","id":5,"text":"hi"}`,
+		},
+		{
+			in:   `{"_":"message","flags":4,"id":5}`,
+			data: words(1, 4, 5),
+			out:  `{"_":"message","flags":4,"id":5}`,
+		},
+		{
+			in:   `{"_":"msg","big":false,"data":""}`,
+			data: words(5, 8, 0xbc799737, 0),
+		},
+		{
+			in:   `{"_":"blob","data":"AQID","nonce":"AAECAwQFBgcICQoLDA0ODw==","ok":true,"ratio":0.5}`,
+			data: cat(words(2, 0x03020103, 0x03020100, 0x07060504, 0x0b0a0908, 0x0f0e0d0c), words(0x997275b5, 0, 0x3fe00000)),
+		},
+		{
+			in:   `{"_":"matrix","rows":[{"a":1,"b":"2"},{"a":3,"b":-1}]}`,
+			data: words(3, 2, 1, 2, 0, 3, 0xffffffff, 0xffffffff),
+			out:  `{"_":"matrix","rows":[{"a":1,"b":"2"},{"a":3,"b":"-1"}]}`,
+		},
+		{
+			in:   `{"_":"pair","ids":["9007199254740993"],"users":[{"_":"userEmpty","id":3}]}`,
+			data: words(4, 1, 1, 0x200000, 0x1cb5c415, 1, 0xc67599d1, 3),
+		},
+		{
+			in:   `{"_":"grid","rows":[{"a":1,"b":2},{"a":3,"b":4}]}`,
+			data: words(7, 1, 2, 1, 2, 3, 4),
+		},
+		{
+			in:   `{"_":"tagged","tag":"x","_1":[1]}`,
+			data: words(6, 0x00007801, 0x1cb5c415, 1, 1),
+		},
+		{
+			in:   `{"_":"invokeWithLayer","layer":23,"query":{"_":"getUsers","_0":[]}}`,
+			data: words(0xda9b0d0d, 23, 0x2d84d5f5, 0x1cb5c415, 0),
+		},
+	}
+
+	for _, tt := range tests {
+		v, err := FromJSON(s, []byte(tt.in))
+		if err != nil {
+			t.Errorf("%s: %v", tt.in, err)
+			continue
+		}
+		data, err := Marshal(s, v)
+		if err != nil {
+			t.Errorf("%s: %v", tt.in, err)
+			continue
+		}
+		if !bytes.Equal(data, tt.data) {
+			t.Errorf("%s:\ngot  % x\nwant % x", tt.in, data, tt.data)
+			continue
+		}
+
+		if v, err = Unmarshal(s, data); err != nil {
+			t.Errorf("%s: %v", tt.in, err)
+			continue
+		}
+		want := tt.out
+		if want == "" {
+			want = tt.in
+		}
+		if got, err := JSON(v); err != nil || string(got) != want {
+			t.Errorf("%s: round trip: got %s, %v, expected %s", tt.in, got, err, want)
+		}
+	}
+}
+
+func TestJSONErrors(t *testing.T) {
+	s := parseSchema(t, testSchema)
+
+	var tests = []struct {
+		in  string
+		err string
+	}{
+		{`{"_":"user"`, "tlbin: json: unexpected EOF"},
+		{`{} {}`, "tlbin: json: unexpected data after top-level value"},
+		{`[1]`, "tlbin: json: cannot use JSON array as object"},
+		{`{"id":2}`, `tlbin: json: missing combinator name "_"`},
+		{`{"_":"nope"}`, "tlbin: json: unknown combinator nope"},
+		{`{"_":"userEmpty","id":2,"name":"x"}`, "tlbin: json: userEmpty: unknown field name"},
+		{`{"_":"getUsers","_0":[],"id":[]}`, "tlbin: json: getUsers: unknown field id"},
+		{`{"_":"tagged","tag":"x","ids":[]}`, "tlbin: json: tagged: unknown field ids"},
+		{`{"_":"tagged","tga":"x","_1":[]}`, "tlbin: json: tagged: unknown field tga"},
+		{`{"_":"userEmpty","id":2147483648}`, "tlbin: json: userEmpty.id: bad int 2147483648"},
+		{`{"_":"userEmpty","id":"2"}`, "tlbin: json: userEmpty.id: cannot use JSON string as int"},
+		{`{"_":"pair","ids":["x"],"users":[]}`, `tlbin: json: pair.ids._2[0]: bad long "x"`},
+		{`{"_":"pair","ids":[],"users":[{"id":3}]}`, `tlbin: json: pair.users._2[0]: missing constructor name "_" of User`},
+		{`{"_":"pair","ids":[],"users":[{"_":"message"}]}`, "tlbin: json: pair.users._2[0]: message is not a constructor of User"},
+		{`{"_":"blob","data":"%"}`, "tlbin: json: blob.data: bad base64 bytes: illegal base64 data at input byte 0"},
+		{`{"_":"blob","nonce":"AQID"}`, "tlbin: json: blob.nonce: got 3 bytes, expected 16"},
+		{`{"_":"matrix","rows":[1]}`, "tlbin: json: matrix.rows[0]: cannot use JSON number as repetition element"},
+	}
+
+	for _, tt := range tests {
+		_, err := FromJSON(s, []byte(tt.in))
+		if err == nil {
+			t.Errorf("%s: expected error %q", tt.in, tt.err)
+			continue
+		}
+		if err.Error() != tt.err {
+			t.Errorf("%s: got error %q, expected %q", tt.in, err, tt.err)
+		}
+	}
+
+	v := Vector{Double(math.Inf(1))}
+	if _, err := JSON(v); err == nil || err.Error() != "tlbin: json: [0]: cannot represent +Inf in JSON" {
+		t.Errorf("JSON of +Inf: got error %v", err)
+	}
+}
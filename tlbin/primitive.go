@@ -0,0 +1,173 @@
+package tlbin
+
+import (
+	"encoding/binary"
+	"math"
+	"strconv"
+)
+
+// The primitives encode and decode the values of the builtin types. Encode
+// functions append the serialization of a value to b and return the extended
+// buffer; decode functions return the value at the start of b along with the
+// number of bytes read. Decode errors are *DecodeError with offsets relative
+// to b.
+
+// MaxStringLen is the length of the longest string or bytes value, whose
+// length must fit in 3 bytes.
+const MaxStringLen = 1<<24 - 1
+
+// EncodeInt appends the serialization of an int.
+func EncodeInt(b []byte, v int32) []byte {
+	var buf [4]byte
+	binary.LittleEndian.PutUint32(buf[:], uint32(v))
+	return append(b, buf[:]...)
+}
+
+// DecodeInt decodes an int.
+func DecodeInt(b []byte) (int32, int, error) {
+	if len(b) < 4 {
+		return 0, 0, errTruncated()
+	}
+	return int32(binary.LittleEndian.Uint32(b)), 4, nil
+}
+
+// EncodeLong appends the serialization of a long.
+func EncodeLong(b []byte, v int64) []byte {
+	return appendUint64(b, uint64(v))
+}
+
+// DecodeLong decodes a long.
+func DecodeLong(b []byte) (int64, int, error) {
+	if len(b) < 8 {
+		return 0, 0, errTruncated()
+	}
+	return int64(binary.LittleEndian.Uint64(b)), 8, nil
+}
+
+// EncodeDouble appends the serialization of a double, an IEEE 754 binary64.
+func EncodeDouble(b []byte, v float64) []byte {
+	return appendUint64(b, math.Float64bits(v))
+}
+
+func appendUint64(b []byte, v uint64) []byte {
+	var buf [8]byte
+	binary.LittleEndian.PutUint64(buf[:], v)
+	return append(b, buf[:]...)
+}
+
+// DecodeDouble decodes a double.
+func DecodeDouble(b []byte) (float64, int, error) {
+	if len(b) < 8 {
+		return 0, 0, errTruncated()
+	}
+	return math.Float64frombits(binary.LittleEndian.Uint64(b)), 8, nil
+}
+
+// EncodeInt128 appends the serialization of an int128, the 16 bytes as is.
+func EncodeInt128(b []byte, v [16]byte) []byte {
+	return append(b, v[:]...)
+}
+
+// DecodeInt128 decodes an int128.
+func DecodeInt128(b []byte) (v [16]byte, n int, err error) {
+	if len(b) < len(v) {
+		return v, 0, errTruncated()
+	}
+	return v, copy(v[:], b), nil
+}
+
+// EncodeInt256 appends the serialization of an int256, the 32 bytes as is.
+func EncodeInt256(b []byte, v [32]byte) []byte {
+	return append(b, v[:]...)
+}
+
+// DecodeInt256 decodes an int256.
+func DecodeInt256(b []byte) (v [32]byte, n int, err error) {
+	if len(b) < len(v) {
+		return v, 0, errTruncated()
+	}
+	return v, copy(v[:], b), nil
+}
+
+// EncodeString appends the serialization of a string: its length in a single
+// byte if shorter than 254 bytes, or the byte 0xfe followed by its length in
+// 3 bytes, then the string, padded with zeros to a multiple of 4 bytes. It
+// returns an error if the string is longer than MaxStringLen bytes.
+func EncodeString(b []byte, s string) ([]byte, error) {
+	n := len(s)
+	switch {
+	case n < 254:
+		b = append(b, byte(n))
+		n++
+	case n <= MaxStringLen:
+		b = append(b, 254, byte(n), byte(n>>8), byte(n>>16))
+		n += 4
+	default:
+		return b, &EncodeError{Msg: "string of " + strconv.Itoa(n) + " bytes is too long"}
+	}
+	b = append(b, s...)
+	for ; n%4 != 0; n++ {
+		b = append(b, 0)
+	}
+	return b, nil
+}
+
+// DecodeString decodes a string, see EncodeString. It rejects lengths
+// encoded in the long form which fit in the short one and non-zero padding.
+func DecodeString(b []byte) (string, int, error) {
+	v, n, err := decodeString(b)
+	return string(v), n, err
+}
+
+// EncodeBytes appends the serialization of bytes, which is the one of
+// strings.
+func EncodeBytes(b, v []byte) ([]byte, error) {
+	return EncodeString(b, string(v))
+}
+
+// DecodeBytes decodes bytes, see DecodeString.
+func DecodeBytes(b []byte) ([]byte, int, error) {
+	v, n, err := decodeString(b)
+	if err != nil {
+		return nil, n, err
+	}
+	return append([]byte{}, v...), n, nil
+}
+
+// decodeString returns the string at the start of b without copying it.
+func decodeString(b []byte) ([]byte, int, error) {
+	if len(b) < 1 {
+		return nil, 0, errTruncated()
+	}
+
+	n, header := int(b[0]), 1
+	switch n {
+	case 254:
+		if len(b) < 4 {
+			return nil, 0, errTruncated()
+		}
+		n, header = int(b[1])|int(b[2])<<8|int(b[3])<<16, 4
+		if n < 254 {
+			return nil, 0, &DecodeError{Msg: "string length " + strconv.Itoa(n) + " in long form"}
+		}
+	case 255:
+		return nil, 0, &DecodeError{Msg: "bad string length prefix 0xff"}
+	}
+
+	size := header + n
+	size += (4 - size%4) % 4
+	if len(b) < size {
+		return nil, 0, &DecodeError{Msg: "unexpected end of input: string of " + strconv.Itoa(n) + " bytes"}
+	}
+	for i := header + n; i < size; i++ {
+		if b[i] != 0 {
+			return nil, 0, &DecodeError{Offset: i, Msg: "bad padding"}
+		}
+	}
+	return b[header : header+n], size, nil
+}
+
+// errTruncated returns the error of a value truncated by the end of input.
+func errTruncated() error {
+	return &DecodeError{Msg: "unexpected end of input"}
+}
@@ -0,0 +1,191 @@
+package tlbin
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestString(t *testing.T) {
+	// every length around the short and long forms and the padding
+	for n := 0; n <= 1030; n++ {
+		if n > 270 && n < 1020 {
+			continue
+		}
+		s := strings.Repeat("x", n)
+
+		b, err := EncodeString(nil, s)
+		if err != nil {
+			t.Fatalf("%d: %v", n, err)
+		}
+
+		header := 1
+		if n >= 254 {
+			header = 4
+			if want := []byte{0xfe, byte(n), byte(n >> 8), 0}; !bytes.Equal(b[:4], want) {
+				t.Errorf("%d: got header % x, expected % x", n, b[:4], want)
+			}
+		} else if b[0] != byte(n) {
+			t.Errorf("%d: got header %x, expected %x", n, b[0], n)
+		}
+		if want := (header + n + 3) / 4 * 4; len(b) != want {
+			t.Errorf("%d: got %d bytes, expected %d", n, len(b), want)
+		}
+		for _, c := range b[header+n:] {
+			if c != 0 {
+				t.Errorf("%d: bad padding % x", n, b[header+n:])
+				break
+			}
+		}
+
+		got, size, err := DecodeString(append(b, 0xff))
+		if err != nil {
+			t.Errorf("%d: %v", n, err)
+			continue
+		}
+		if got != s || size != len(b) {
+			t.Errorf("%d: got a string of %d bytes reading %d bytes, expected %d", n, len(got), size, len(b))
+		}
+	}
+}
+
+func TestStringBoundary(t *testing.T) {
+	var tests = []struct {
+		n    int
+		want []byte // header
+		size int
+	}{
+		{253, []byte{0xfd}, 256},
+		{254, []byte{0xfe, 0xfe, 0x00, 0x00}, 260},
+		{255, []byte{0xfe, 0xff, 0x00, 0x00}, 260},
+		{256, []byte{0xfe, 0x00, 0x01, 0x00}, 260},
+		{257, []byte{0xfe, 0x01, 0x01, 0x00}, 264},
+	}
+
+	for _, tt := range tests {
+		b, err := EncodeBytes(nil, bytes.Repeat([]byte{1}, tt.n))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.HasPrefix(b, tt.want) || len(b) != tt.size {
+			t.Errorf("%d: got % x... of %d bytes, expected % x... of %d bytes", tt.n, b[:len(tt.want)], len(b), tt.want, tt.size)
+		}
+
+		v, size, err := DecodeBytes(b)
+		if err != nil || len(v) != tt.n || size != tt.size {
+			t.Errorf("%d: got %d bytes reading %d, %v", tt.n, len(v), size, err)
+		}
+	}
+
+	if _, err := EncodeString(nil, strings.Repeat("x", MaxStringLen+1)); err == nil {
+		t.Errorf("expected error encoding a string of %d bytes", MaxStringLen+1)
+	}
+	b, err := EncodeString(nil, strings.Repeat("x", MaxStringLen))
+	if err != nil || !bytes.HasPrefix(b, []byte{0xfe, 0xff, 0xff, 0xff}) {
+		t.Errorf("got % x..., %v encoding a string of %d bytes", b[:4], err, MaxStringLen)
+	}
+}
+
+func TestDecodeStringErrors(t *testing.T) {
+	var tests = []struct {
+		b   []byte
+		err string
+	}{
+		{nil, "tlbin: offset 0: unexpected end of input"},
+		{[]byte{0x02, 'h'}, "tlbin: offset 0: unexpected end of input: string of 2 bytes"},
+		{[]byte{0x02, 'h', 'i'}, "tlbin: offset 0: unexpected end of input: string of 2 bytes"},
+		{[]byte{0x02, 'h', 'i', 0x01}, "tlbin: offset 3: bad padding"},
+		{[]byte{0x00, 0x00, 0x00, 0x20}, "tlbin: offset 3: bad padding"},
+		{[]byte{0xfe, 0x01, 0x00}, "tlbin: offset 0: unexpected end of input"},
+		{[]byte{0xfe, 0x01, 0x00, 0x00, 'x', 0, 0, 0}, "tlbin: offset 0: string length 1 in long form"},
+		{[]byte{0xfe, 0xfd, 0x00, 0x00}, "tlbin: offset 0: string length 253 in long form"},
+		{[]byte{0xfe, 0xfe, 0x00, 0x00, 'x'}, "tlbin: offset 0: unexpected end of input: string of 254 bytes"},
+		{[]byte{0xff, 0x00, 0x00, 0x00}, "tlbin: offset 0: bad string length prefix 0xff"},
+	}
+
+	for _, tt := range tests {
+		_, _, err := DecodeString(tt.b)
+		if err == nil {
+			t.Errorf("% x: expected error %q", tt.b, tt.err)
+			continue
+		}
+		if err.Error() != tt.err {
+			t.Errorf("% x: got error %q, expected %q", tt.b, err, tt.err)
+		}
+	}
+
+	// padding of long strings
+	b, _ := EncodeString(nil, strings.Repeat("x", 255))
+	b[len(b)-1] = 1
+	if _, _, err := DecodeString(b); err == nil || err.Error() != "tlbin: offset 259: bad padding" {
+		t.Errorf("got error %v, expected bad padding at offset 259", err)
+	}
+}
+
+func TestNumbers(t *testing.T) {
+	b := EncodeInt(nil, -2)
+	b = EncodeLong(b, math.MinInt64)
+	b = EncodeDouble(b, -1.5)
+	want := []byte{
+		0xfe, 0xff, 0xff, 0xff,
+		0, 0, 0, 0, 0, 0, 0, 0x80,
+		0, 0, 0, 0, 0, 0, 0xf8, 0xbf,
+	}
+	if !bytes.Equal(b, want) {
+		t.Fatalf("got % x, expected % x", b, want)
+	}
+
+	i, n, err := DecodeInt(b)
+	if i != -2 || n != 4 || err != nil {
+		t.Errorf("DecodeInt: got %d, %d, %v", i, n, err)
+	}
+	l, n, err := DecodeLong(b[4:])
+	if l != math.MinInt64 || n != 8 || err != nil {
+		t.Errorf("DecodeLong: got %d, %d, %v", l, n, err)
+	}
+	d, n, err := DecodeDouble(b[12:])
+	if d != -1.5 || n != 8 || err != nil {
+		t.Errorf("DecodeDouble: got %g, %d, %v", d, n, err)
+	}
+
+	if _, _, err := DecodeInt(b[:3]); err == nil {
+		t.Error("DecodeInt: expected error on 3 bytes")
+	}
+	if _, _, err := DecodeLong(b[:7]); err == nil {
+		t.Error("DecodeLong: expected error on 7 bytes")
+	}
+	if _, _, err := DecodeDouble(b[:7]); err == nil {
+		t.Error("DecodeDouble: expected error on 7 bytes")
+	}
+}
+
+func TestIntN(t *testing.T) {
+	var x128 [16]byte
+	var x256 [32]byte
+	for i := range x256 {
+		x256[i] = byte(i + 1)
+	}
+	copy(x128[:], x256[:])
+
+	b := EncodeInt256(EncodeInt128(nil, x128), x256)
+	if len(b) != 48 || !bytes.Equal(b[:16], x128[:]) || !bytes.Equal(b[16:], x256[:]) {
+		t.Fatalf("got % x", b)
+	}
+
+	v128, n, err := DecodeInt128(b)
+	if v128 != x128 || n != 16 || err != nil {
+		t.Errorf("DecodeInt128: got % x, %d, %v", v128, n, err)
+	}
+	v256, n, err := DecodeInt256(b[16:])
+	if v256 != x256 || n != 32 || err != nil {
+		t.Errorf("DecodeInt256: got % x, %d, %v", v256, n, err)
+	}
+
+	if _, _, err := DecodeInt128(b[:15]); err == nil {
+		t.Error("DecodeInt128: expected error on 15 bytes")
+	}
+	if _, _, err := DecodeInt256(b[:31]); err == nil {
+		t.Error("DecodeInt256: expected error on 31 bytes")
+	}
+}
@@ -0,0 +1,344 @@
+package tlbin
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/igungor/tl"
+)
+
+// A Value is a TL value: an *Object, a Vector, an Int, a Long, a Double, a
+// String, a Bytes, an Int128, an Int256 or a Bool. Values of # are Ints.
+//
+// The accessors return the zero value for values of other kinds, e.g. Int of
+// a String returns 0 and Field of a Vector returns Missing, so that they can
+// be chained as in v.Field("user").Field("id").Int(), which returns 0 if there
+// is no user. Query reports missing fields and indices instead.
+type Value interface {
+	Field(name string) Value // field of an object, or Missing
+	Index(i int) Value       // element of a vector, or Missing if out of range
+	Len() int                // number of elements of a vector
+	Int() int64              // value of an Int or a Long
+	Float() float64          // value of a Double, an Int or a Long
+	Text() string            // value of a String or a Bytes
+	Bytes() []byte           // value of a Bytes, a String, an Int128 or an Int256
+	Bool() bool              // value of a Bool
+	String() string          // TL-like text, e.g. user{id:2 first_name:"Peter"}
+}
+
+// Object is a value of a combinator: a constructor or a function call. The
+// elements of repetitions of several fields, e.g. [a:int b:long], are objects
+// of neither.
+//
+// Fields are keyed by field name, or by _N for the anonymous N-th field of
+// the combinator, e.g. _0 for the (Vector int) of getUsers. # fields may be
+// omitted when encoding; they are computed from the conditional fields
+// present and the length of the repetitions.
+type Object struct {
+	Constructor *tl.Constructor // constructor of the value, or nil
+	Function    *tl.Function    // function called, or nil
+	Fields      map[string]Value
+}
+
+// Missing is the value of missing fields and elements returned by Field and
+// Index: a nil *Object, whose accessors return zero values.
+var Missing Value = (*Object)(nil)
+
+type (
+	Vector []Value  // Vector t, or a repetition
+	Int    int32    // int or #
+	Long   int64    // long
+	Double float64  // double
+	String string   // string
+	Bytes  []byte   // bytes
+	Int128 [16]byte // int128
+	Int256 [32]byte // int256
+	Bool   bool     // Bool, or true
+)
+
+// NewObject returns the object of the constructor or function of s with the
+// given name.
+func NewObject(s *tl.Schema, name string, fields map[string]Value) (*Object, error) {
+	if c := s.Constructor(name); c != nil {
+		return &Object{Constructor: c, Fields: fields}, nil
+	}
+	if f := s.Function(name); f != nil {
+		return &Object{Function: f, Fields: fields}, nil
+	}
+	return nil, fmt.Errorf("tlbin: unknown combinator %s", name)
+}
+
+// Name returns the name of the combinator of the object, or the empty string.
+func (o *Object) Name() string {
+	switch {
+	case o == nil:
+		return ""
+	case o.Constructor != nil:
+		return o.Constructor.Name()
+	case o.Function != nil:
+		return o.Function.Name()
+	}
+	return ""
+}
+
+// combFields returns the fields of the combinator of the object, or nil.
+func (o *Object) combFields() []*tl.Field {
+	switch {
+	case o == nil:
+		return nil
+	case o.Constructor != nil:
+		return o.Constructor.Fields
+	case o.Function != nil:
+		return o.Function.Fields
+	}
+	return nil
+}
+
+func (o *Object) Field(name string) Value {
+	if o == nil {
+		return Missing
+	}
+	if v := o.Fields[name]; v != nil {
+		return v
+	}
+	return Missing
+}
+func (Vector) Field(string) Value { return Missing }
+func (Int) Field(string) Value    { return Missing }
+func (Long) Field(string) Value   { return Missing }
+func (Double) Field(string) Value { return Missing }
+func (String) Field(string) Value { return Missing }
+func (Bytes) Field(string) Value  { return Missing }
+func (Int128) Field(string) Value { return Missing }
+func (Int256) Field(string) Value { return Missing }
+func (Bool) Field(string) Value   { return Missing }
+
+func (*Object) Index(int) Value { return Missing }
+func (v Vector) Index(i int) Value {
+	if i < 0 || i >= len(v) || v[i] == nil {
+		return Missing
+	}
+	return v[i]
+}
+func (Int) Index(int) Value    { return Missing }
+func (Long) Index(int) Value   { return Missing }
+func (Double) Index(int) Value { return Missing }
+func (String) Index(int) Value { return Missing }
+func (Bytes) Index(int) Value  { return Missing }
+func (Int128) Index(int) Value { return Missing }
+func (Int256) Index(int) Value { return Missing }
+func (Bool) Index(int) Value   { return Missing }
+
+func (*Object) Len() int  { return 0 }
+func (v Vector) Len() int { return len(v) }
+func (Int) Len() int      { return 0 }
+func (Long) Len() int     { return 0 }
+func (Double) Len() int   { return 0 }
+func (String) Len() int   { return 0 }
+func (Bytes) Len() int    { return 0 }
+func (Int128) Len() int   { return 0 }
+func (Int256) Len() int   { return 0 }
+func (Bool) Len() int     { return 0 }
+
+func (*Object) Int() int64 { return 0 }
+func (Vector) Int() int64  { return 0 }
+func (v Int) Int() int64   { return int64(v) }
+func (v Long) Int() int64  { return int64(v) }
+func (Double) Int() int64  { return 0 }
+func (String) Int() int64  { return 0 }
+func (Bytes) Int() int64   { return 0 }
+func (Int128) Int() int64  { return 0 }
+func (Int256) Int() int64  { return 0 }
+func (Bool) Int() int64    { return 0 }
+
+func (*Object) Float() float64  { return 0 }
+func (Vector) Float() float64   { return 0 }
+func (v Int) Float() float64    { return float64(v) }
+func (v Long) Float() float64   { return float64(v) }
+func (v Double) Float() float64 { return float64(v) }
+func (String) Float() float64   { return 0 }
+func (Bytes) Float() float64    { return 0 }
+func (Int128) Float() float64   { return 0 }
+func (Int256) Float() float64   { return 0 }
+func (Bool) Float() float64     { return 0 }
+
+func (*Object) Text() string  { return "" }
+func (Vector) Text() string   { return "" }
+func (Int) Text() string      { return "" }
+func (Long) Text() string     { return "" }
+func (Double) Text() string   { return "" }
+func (v String) Text() string { return string(v) }
+func (v Bytes) Text() string  { return string(v) }
+func (Int128) Text() string   { return "" }
+func (Int256) Text() string   { return "" }
+func (Bool) Text() string     { return "" }
+
+func (*Object) Bytes() []byte  { return nil }
+func (Vector) Bytes() []byte   { return nil }
+func (Int) Bytes() []byte      { return nil }
+func (Long) Bytes() []byte     { return nil }
+func (Double) Bytes() []byte   { return nil }
+func (v String) Bytes() []byte { return []byte(v) }
+func (v Bytes) Bytes() []byte  { return v }
+func (v Int128) Bytes() []byte { return v[:] }
+func (v Int256) Bytes() []byte { return v[:] }
+func (Bool) Bytes() []byte     { return nil }
+
+func (*Object) Bool() bool { return false }
+func (Vector) Bool() bool  { return false }
+func (Int) Bool() bool     { return false }
+func (Long) Bool() bool    { return false }
+func (Double) Bool() bool  { return false }
+func (String) Bool() bool  { return false }
+func (Bytes) Bool() bool   { return false }
+func (Int128) Bool() bool  { return false }
+func (Int256) Bool() bool  { return false }
+func (v Bool) Bool() bool  { return bool(v) }
+
+// String returns the object as the name of its combinator followed by its
+// fields in declaration order, e.g. user{id:2 first_name:"Peter"}. A nil
+// object, e.g. Missing, is "nil".
+func (o *Object) String() string {
+	if o == nil {
+		return "nil"
+	}
+	var keys []string
+	if fields := o.combFields(); fields != nil {
+		for i, f := range fields {
+			if _, ok := o.Fields[fieldKey(f, i)]; ok {
+				keys = append(keys, fieldKey(f, i))
+			}
+		}
+	} else {
+		for key := range o.Fields {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+	}
+
+	var b strings.Builder
+	b.WriteString(o.Name())
+	if len(keys) == 0 && o.Name() != "" {
+		return b.String()
+	}
+	b.WriteString("{")
+	for i, key := range keys {
+		if i > 0 {
+			b.WriteString(" ")
+		}
+		b.WriteString(key)
+		b.WriteString(":")
+		b.WriteString(valueString(o.Fields[key]))
+	}
+	b.WriteString("}")
+	return b.String()
+}
+
+func (v Vector) String() string {
+	elems := make([]string, len(v))
+	for i, x := range v {
+		elems[i] = valueString(x)
+	}
+	return "[" + strings.Join(elems, " ") + "]"
+}
+
+func (v Int) String() string    { return strconv.FormatInt(int64(v), 10) }
+func (v Long) String() string   { return strconv.FormatInt(int64(v), 10) }
+func (v Double) String() string { return strconv.FormatFloat(float64(v), 'g', -1, 64) }
+func (v String) String() string { return strconv.Quote(string(v)) }
+func (v Bytes) String() string  { return "0x" + hex.EncodeToString(v) }
+func (v Int128) String() string { return "0x" + hex.EncodeToString(v[:]) }
+func (v Int256) String() string { return "0x" + hex.EncodeToString(v[:]) }
+func (v Bool) String() string   { return strconv.FormatBool(bool(v)) }
+
+func valueString(v Value) string {
+	if v == nil {
+		return "nil"
+	}
+	return v.String()
+}
+
+// Query returns the value at the given path in v: field names separated by
+// dots, each followed by any number of vector indices, e.g.
+// users[0].first_name. A path starting with an index, e.g. [0].id, indexes
+// v itself.
+func Query(v Value, path string) (Value, error) {
+	errorf := func(format string, args ...interface{}) error {
+		return fmt.Errorf("tlbin: query %s: %s", path, fmt.Sprintf(format, args...))
+	}
+
+	rest := path
+	for first := true; rest != ""; first = false {
+		if !first || rest[0] != '[' {
+			if !first {
+				if rest[0] != '.' {
+					return nil, errorf("unexpected %q", rest[0])
+				}
+				rest = rest[1:]
+			}
+			i := strings.IndexAny(rest, ".[]")
+			if i < 0 {
+				i = len(rest)
+			}
+			name := rest[:i]
+			rest = rest[i:]
+			if name == "" {
+				return nil, errorf("missing field name")
+			}
+
+			o, ok := v.(*Object)
+			if !ok || o == nil {
+				return nil, errorf("cannot select field %s of %s", name, kind(v))
+			}
+			if v = o.Fields[name]; v == nil {
+				return nil, errorf("%s has no field %s", kind(o), name)
+			}
+		}
+
+		for strings.HasPrefix(rest, "[") {
+			end := strings.IndexByte(rest, ']')
+			if end < 0 {
+				return nil, errorf("missing ]")
+			}
+			i, err := strconv.Atoi(rest[1:end])
+			if err != nil {
+				return nil, errorf("bad index %q", rest[1:end])
+			}
+			rest = rest[end+1:]
+
+			vec, ok := v.(Vector)
+			if !ok {
+				return nil, errorf("cannot index %s", kind(v))
+			}
+			if i < 0 || i >= len(vec) {
+				return nil, errorf("index %d out of range [0:%d]", i, len(vec))
+			}
+			v = vec[i]
+		}
+	}
+	if v == nil {
+		return nil, errors.New("tlbin: query of nil value")
+	}
+	return v, nil
+}
+
+// kind describes the value v in errors, e.g. user or Vector.
+func kind(v Value) string {
+	switch v := v.(type) {
+	case *Object:
+		if v == nil {
+			return "nil"
+		}
+		if name := v.Name(); name != "" {
+			return name
+		}
+		return "object"
+	case nil:
+		return "nil"
+	}
+	return strings.TrimPrefix(fmt.Sprintf("%T", v), "tlbin.")
+}
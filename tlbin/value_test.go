@@ -0,0 +1,117 @@
+package tlbin
+
+import "testing"
+
+func TestValue(t *testing.T) {
+	s := parseSchema(t, testSchema)
+
+	v := obj(t, s, "pair", map[string]Value{
+		"ids": Vector{Long(7), Long(-1)},
+		"users": Vector{
+			obj(t, s, "user", map[string]Value{"id": Int(2), "first_name": String("Peter"), "last_name": String("Parker")}),
+			obj(t, s, "userEmpty", map[string]Value{"id": Int(3)}),
+		},
+	})
+
+	if got := v.Field("users").Index(0).Field("id").Int(); got != 2 {
+		t.Errorf("users[0].id: got %d, expected 2", got)
+	}
+	if got := v.Field("users").Index(0).Field("first_name").Text(); got != "Peter" {
+		t.Errorf("users[0].first_name: got %q, expected Peter", got)
+	}
+	if got := v.Field("ids").Len(); got != 2 {
+		t.Errorf("len(ids): got %d, expected 2", got)
+	}
+	if got := v.Field("ids").Index(1).Int(); got != -1 {
+		t.Errorf("ids[1]: got %d, expected -1", got)
+	}
+	if got := v.Field("ids").Index(2); got != Missing {
+		t.Errorf("ids[2]: got %v, expected Missing", got)
+	}
+	if got := v.Field("ids").Text(); got != "" {
+		t.Errorf("ids as text: got %q", got)
+	}
+	if got := v.Field("nope"); got != Missing {
+		t.Errorf("nope: got %v, expected Missing", got)
+	}
+
+	// chains through missing fields, elements and non-objects
+	if got := v.Field("nope").Field("id").Index(0).Int(); got != 0 {
+		t.Errorf("nope.id[0]: got %d, expected 0", got)
+	}
+	if got := v.Field("users").Index(1).Field("first_name").Text(); got != "" {
+		t.Errorf("users[1].first_name: got %q, expected empty", got)
+	}
+	if got := v.Field("ids").Index(5).Field("id").Field("x"); got != Missing || got.String() != "nil" || got.Len() != 0 {
+		t.Errorf("ids[5].id.x: got %v, expected Missing", got)
+	}
+	var nilObj *Object
+	if got := nilObj.Field("id").Int(); got != 0 || nilObj.Name() != "" {
+		t.Errorf("field of nil object: got %d, name %q", got, nilObj.Name())
+	}
+	if u := v.Field("users").Index(1).(*Object); u.Constructor != s.Constructor("userEmpty") || u.Name() != "userEmpty" {
+		t.Errorf("users[1]: got constructor %v", u.Constructor)
+	}
+
+	want := `pair{ids:[7 -1] users:[user{id:2 first_name:"Peter" last_name:"Parker"} userEmpty{id:3}]}`
+	if got := v.String(); got != want {
+		t.Errorf("String:\ngot  %s\nwant %s", got, want)
+	}
+	if got := (Vector{Bytes{1, 0xab}, Bool(true), Double(0.25), &Object{Fields: map[string]Value{"b": Int(1), "a": Int(0)}}}).String(); got != "[0x01ab true 0.25 {a:0 b:1}]" {
+		t.Errorf("String: got %s", got)
+	}
+}
+
+func TestQuery(t *testing.T) {
+	s := parseSchema(t, testSchema)
+
+	v := obj(t, s, "pair", map[string]Value{
+		"ids": Vector{Long(7)},
+		"users": Vector{
+			obj(t, s, "user", map[string]Value{"id": Int(2), "first_name": String("Peter"), "last_name": String("Parker")}),
+		},
+	})
+
+	var tests = []struct {
+		path string
+		want string
+		err  string
+	}{
+		{"", v.String(), ""},
+		{"ids", "[7]", ""},
+		{"ids[0]", "7", ""},
+		{"users[0].first_name", `"Peter"`, ""},
+		{"users[0]", `user{id:2 first_name:"Peter" last_name:"Parker"}`, ""},
+		{"users[1]", "", "tlbin: query users[1]: index 1 out of range [0:1]"},
+		{"users[0].photo", "", "tlbin: query users[0].photo: user has no field photo"},
+		{"ids.x", "", "tlbin: query ids.x: cannot select field x of Vector"},
+		{"ids[0][0]", "", "tlbin: query ids[0][0]: cannot index Long"},
+		{"ids[x]", "", `tlbin: query ids[x]: bad index "x"`},
+		{"ids[0", "", "tlbin: query ids[0: missing ]"},
+		{"users..id", "", "tlbin: query users..id: missing field name"},
+		{"ids]", "", `tlbin: query ids]: unexpected ']'`},
+	}
+
+	for _, tt := range tests {
+		got, err := Query(v, tt.path)
+		if tt.err != "" {
+			if err == nil || err.Error() != tt.err {
+				t.Errorf("%q: got error %v, expected %q", tt.path, err, tt.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: %v", tt.path, err)
+			continue
+		}
+		if got.String() != tt.want {
+			t.Errorf("%q: got %s, expected %s", tt.path, got, tt.want)
+		}
+	}
+
+	// paths into vectors
+	users, _ := Query(v, "users")
+	if got, err := Query(users, "[0].id"); err != nil || got.Int() != 2 {
+		t.Errorf("[0].id: got %v, %v", got, err)
+	}
+}
@@ -2,8 +2,50 @@
 
 package tl
 
-import "fmt"
-import "strings"
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// Pos describes an arbitrary source position including the file, line and
+// column location. A Pos is valid if the line number is > 0.
+type Pos struct {
+	Filename string // filename, if any
+	Offset   int    // offset, starting at 0
+	Line     int    // line number, starting at 1
+	Column   int    // column number, starting at 1 (character count)
+}
+
+// IsValid reports whether the position is valid.
+func (p Pos) IsValid() bool { return p.Line > 0 }
+
+func (p Pos) String() string {
+	if !p.IsValid() {
+		if p.Filename != "" {
+			return p.Filename
+		}
+		return "-"
+	}
+	if p.Filename != "" {
+		return fmt.Sprintf("%s:%d:%d", p.Filename, p.Line, p.Column)
+	}
+	return fmt.Sprintf("%d:%d", p.Line, p.Column)
+}
+
+// advance returns the position immediately after s, assuming s starts at p.
+func (p Pos) advance(s string) Pos {
+	for _, ch := range s {
+		p.Offset += utf8.RuneLen(ch)
+		if ch == '\n' {
+			p.Line++
+			p.Column = 1
+		} else {
+			p.Column++
+		}
+	}
+	return p
+}
 
 // Token represents a lexical token.
 type Token struct {
@@ -34,16 +76,21 @@ func (t Token) HasName() bool {
 
 // HasNamespace reports whether the token literal is lc-ident-ns or uc-ident-ns.
 func (t Token) HasNamespace() bool {
-	// only lc-ident-full has a name
-	if t.Token != ItemLowerIdent || t.Token != ItemUpperIdent {
-		return false
+	return t.Namespace() != ""
+}
+
+// Namespace returns the namespace of an lc-ident-ns or uc-ident-ns, e.g.
+// messages for messages.sendMessage#fa88427a, or the empty string.
+func (t Token) Namespace() string {
+	if t.Token != ItemLowerIdent && t.Token != ItemUpperIdent {
+		return ""
 	}
 
-	fields := strings.Split(t.Literal, ".")
-	if len(fields) == 1 || (len(fields) == 2 && fields[1] == "") {
-		return false
+	name := t.Literal
+	if i := strings.IndexByte(name, '#'); i >= 0 {
+		name = name[:i]
 	}
-	return true
+	return namespace(name)
 }
 
 // Item represents a lexical token type.
@@ -53,6 +100,7 @@ type Item int
 const (
 	ItemIllegal Item = iota
 	ItemWhitespace
+	ItemComment
 	ItemEOF
 
 	ItemUnderscore   // _
@@ -4,9 +4,9 @@ package tl
 
 import "fmt"
 
-const _Item_name = "ItemIllegalItemWhitespaceItemEOFItemUnderscoreItemColonItemSemicolonItemOpenParItemCloseParItemOpenBracketItemCloseBracketItemOpenBraceItemCloseBraceItemLeftAngleItemRightAngleItemTripleMinusItemEqualsItemHashItemExclMarkItemQuestionMarkItemPercentItemPlusItemCommaItemDotItemAsteriskItemNatConstItemLowerIdentItemUpperIdentItemFinalItemNewItemEmpty"
+const _Item_name = "ItemIllegalItemWhitespaceItemCommentItemEOFItemUnderscoreItemColonItemSemicolonItemOpenParItemCloseParItemOpenBracketItemCloseBracketItemOpenBraceItemCloseBraceItemLeftAngleItemRightAngleItemTripleMinusItemEqualsItemHashItemExclMarkItemQuestionMarkItemPercentItemPlusItemCommaItemDotItemAsteriskItemNatConstItemLowerIdentItemUpperIdentItemFinalItemNewItemEmpty"
 
-var _Item_index = [...]uint16{0, 11, 25, 32, 46, 55, 68, 79, 91, 106, 122, 135, 149, 162, 176, 191, 201, 209, 221, 237, 248, 256, 265, 272, 284, 296, 310, 324, 333, 340, 349}
+var _Item_index = [...]uint16{0, 11, 25, 36, 43, 57, 66, 79, 90, 102, 117, 133, 146, 160, 173, 187, 202, 212, 220, 232, 248, 259, 267, 276, 283, 295, 307, 321, 335, 344, 351, 360}
 
 func (i Item) String() string {
 	if i < 0 || i+1 >= Item(len(_Item_index)) {
@@ -0,0 +1,17 @@
+package tl
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestItem_String(t *testing.T) {
+	for item := ItemIllegal; item <= ItemEmpty; item++ {
+		if s := item.String(); strings.HasPrefix(s, "Item(") {
+			t.Errorf("item %d has no name: %s", int(item), s)
+		}
+	}
+	if got := ItemLowerIdent.String(); got != "ItemLowerIdent" {
+		t.Errorf("ItemLowerIdent: got %s", got)
+	}
+}
@@ -0,0 +1,257 @@
+package tl
+
+import "fmt"
+
+// kind classifies the parameters of polymorphic types and the variables of
+// combinators: a parameter is either a type, e.g. the t of Vector t, or a
+// natural number, e.g. the n of Tuple t n.
+type kind int
+
+const (
+	kindInvalid kind = iota
+	kindType
+	kindNat
+)
+
+// typeChecker checks that the type expressions of the combinators of a
+// resolved schema are well-formed.
+type typeChecker struct {
+	s      *Schema
+	errs   *ErrorList
+	params map[*Type][]kind
+}
+
+// typecheck checks the declarations of s. Unresolved references are skipped,
+// they are reported by the resolver.
+func (s *Schema) typecheck(errs *ErrorList) {
+	c := &typeChecker{s: s, errs: errs, params: make(map[*Type][]kind)}
+
+	for _, con := range s.Constructors {
+		if d, ok := con.Decl.(*CombDecl); ok {
+			c.decl(d)
+		}
+	}
+	for _, f := range s.Functions {
+		c.decl(f.Decl)
+	}
+}
+
+func (c *typeChecker) errorf(pos Pos, format string, args ...interface{}) {
+	c.errs.Add(pos, fmt.Sprintf(format, args...))
+}
+
+func (c *typeChecker) decl(d *CombDecl) {
+	for _, arg := range d.OptArgs {
+		if k := c.sort(arg.Type); k == kindInvalid {
+			c.errorf(arg.Type.Pos(), "optional argument %s must be of type Type or #, not %s",
+				identList(arg.Names), exprString(arg.Type))
+		}
+		if arg.Excl.IsValid() {
+			c.errorf(arg.Excl, "invalid ! in optional argument %s", identList(arg.Names))
+		}
+	}
+
+	// the multiplicity of a leading repetition may be an optional
+	// argument, e.g. tuple {t:Type} {n:#} [t] = Tuple t n
+	var prev Expr
+	if n := len(d.OptArgs); n > 0 {
+		prev = d.OptArgs[n-1].Type
+	}
+	c.args(d.Args, prev)
+	c.result(d.Result)
+}
+
+// args checks a list of arguments. prev is the type of the field preceding
+// the list, if any.
+func (c *typeChecker) args(list []*Arg, prev Expr) {
+	for _, arg := range list {
+		if arg.Excl.IsValid() {
+			v, ok := arg.Type.(*Var)
+			if !ok || c.varKind(v) != kindType {
+				c.errorf(arg.Excl, "! must be followed by a type variable, not %s", exprString(arg.Type))
+				prev = arg.Type
+				continue
+			}
+		}
+
+		if rep, ok := arg.Type.(*Repetition); ok && rep.Mult == nil {
+			// the multiplicity defaults to the preceding field, e.g. # [t]
+			if prev == nil || c.sort(prev) != kindNat {
+				c.errorf(rep.Pos(), "repetition without multiplicity must follow a field of type #")
+			}
+		}
+
+		c.typeExpr(arg.Type)
+		prev = arg.Type
+	}
+}
+
+// result checks the result type of a combinator against the parameters of
+// the type, which are determined by its first constructor.
+func (c *typeChecker) result(r *ResultType) {
+	switch obj := c.s.Uses[r.Name].(type) {
+	case *TypeVar:
+		if k := c.sort(obj.Type); k != kindType {
+			c.errorf(r.Name.Pos(), "%s is not a type variable", r.Name.Name)
+		}
+		if len(r.Args) > 0 {
+			c.errorf(r.Name.Pos(), "cannot apply type variable %s", r.Name.Name)
+		}
+	case *Type:
+		c.apply(r.Name.Pos(), obj, r.Args)
+	}
+}
+
+// typeExpr checks that x is a fully applied type.
+func (c *typeChecker) typeExpr(x Expr) {
+	switch x := x.(type) {
+	case *TypeIdent:
+		if t := c.typeOf(x); t != nil {
+			if n := len(c.paramsOf(t)); n > 0 {
+				c.errorf(x.Pos(), "%s requires %d argument(s)", x.Name, n)
+			}
+		}
+	case *Var:
+		if c.s.Uses[x] != nil && c.varKind(x) != kindType {
+			c.errorf(x.Pos(), "%s is not a type", x.Name)
+		}
+	case *BareType:
+		c.typeExpr(x.X)
+	case *ParenExpr:
+		c.typeExpr(x.X)
+	case *AppExpr:
+		c.app(x)
+	case *Repetition:
+		if x.Mult != nil {
+			c.natExpr(x.Mult)
+		}
+		c.args(x.Args, nil)
+	default:
+		c.errorf(x.Pos(), "%s is not a type", exprString(x))
+	}
+}
+
+// natExpr checks that x is a nat expression, a sum of nat constants and nat
+// variables.
+func (c *typeChecker) natExpr(x Expr) {
+	switch x := x.(type) {
+	case *NatConst:
+	case *Var:
+		if c.s.Uses[x] != nil && c.varKind(x) != kindNat {
+			c.errorf(x.Pos(), "%s is not of type #", x.Name)
+		}
+	case *SumExpr:
+		c.natExpr(x.X)
+		c.natExpr(x.Y)
+	case *ParenExpr:
+		c.natExpr(x.X)
+	default:
+		c.errorf(x.Pos(), "%s is not a nat expression", exprString(x))
+	}
+}
+
+// app checks a type application, e.g. Vector<int>.
+func (c *typeChecker) app(x *AppExpr) {
+	fun := x.Fun
+	if b, ok := fun.(*BareType); ok {
+		fun = b.X
+	}
+
+	switch f := fun.(type) {
+	case *TypeIdent:
+		if t := c.typeOf(f); t != nil {
+			c.apply(f.Pos(), t, x.Args)
+		}
+	case *Var:
+		c.errorf(f.Pos(), "cannot apply type variable %s", f.Name)
+	default:
+		c.errorf(f.Pos(), "cannot apply %s", exprString(f))
+	}
+}
+
+// apply checks the arguments of the type t applied at pos.
+func (c *typeChecker) apply(pos Pos, t *Type, args []Expr) {
+	params := c.paramsOf(t)
+	if len(args) != len(params) {
+		c.errorf(pos, "wrong number of arguments for %s: got %d, expected %d", t.Name(), len(args), len(params))
+		return
+	}
+
+	for i, arg := range args {
+		if params[i] == kindNat {
+			c.natExpr(arg)
+		} else {
+			c.typeExpr(arg)
+		}
+	}
+}
+
+// typeOf returns the type the identifier denotes, the type of the
+// constructor for bare types. It returns nil for builtins and unresolved
+// identifiers.
+func (c *typeChecker) typeOf(x *TypeIdent) *Type {
+	switch obj := c.s.Uses[x].(type) {
+	case *Type:
+		return obj
+	case *Constructor:
+		return obj.Type
+	}
+	return nil
+}
+
+// paramsOf returns the kinds of the parameters of t, as declared by the result
+// type of its first constructor, e.g. [kindType, kindNat] for
+// `tuple {t:Type} {n:#} [t] = Tuple t n`.
+func (c *typeChecker) paramsOf(t *Type) []kind {
+	if params, ok := c.params[t]; ok {
+		return params
+	}
+
+	var params []kind
+	for _, con := range t.Constructors {
+		d, ok := con.Decl.(*CombDecl)
+		if !ok {
+			continue
+		}
+		for _, arg := range d.Result.Args {
+			k := kindType
+			switch x := arg.(type) {
+			case *Var:
+				k = c.varKind(x)
+			case *NatConst, *SumExpr:
+				k = kindNat
+			}
+			params = append(params, k)
+		}
+		break
+	}
+
+	c.params[t] = params
+	return params
+}
+
+// varKind returns the kind of a variable: kindType for variables of type
+// Type, kindNat for variables of type #.
+func (c *typeChecker) varKind(x *Var) kind {
+	v, ok := c.s.Uses[x].(*TypeVar)
+	if !ok {
+		return kindInvalid
+	}
+	return c.sort(v.Type)
+}
+
+// sort returns kindType if x is Type, kindNat if x is #.
+func (c *typeChecker) sort(x Expr) kind {
+	ident, ok := x.(*TypeIdent)
+	if !ok {
+		return kindInvalid
+	}
+
+	switch c.s.Uses[ident] {
+	case TypeType:
+		return kindType
+	case Nat:
+		return kindNat
+	}
+	return kindInvalid
+}
@@ -0,0 +1,85 @@
+package tl
+
+import "testing"
+
+func TestChecker_typecheck(t *testing.T) {
+	const prelude = `
+vector#1cb5c415 {t:Type} # [ t ] = Vector t;
+tuple {t:Type} {n:#} [t] = Tuple t n;
+user id:int = User;
+`
+
+	tests := []struct {
+		src  string
+		errs []string
+	}{
+		{"", nil},
+		{"users n:# list:Tuple<User,n> = Users;", nil},
+		{"users n:# list:(Tuple User (n+1)) = Users;", nil},
+		{"---functions---\ninvokeWithLayer {X:Type} layer:int query:!X = X;", nil},
+		{"users n:# list:n*[User] = Users;", nil},
+		{
+			"users {t:int} = Users;",
+			[]string{"5:10: optional argument t must be of type Type or #, not int"},
+		},
+		{
+			"users list:Vector = Users;",
+			[]string{"5:12: Vector requires 1 argument(s)"},
+		},
+		{
+			"users list:Vector<User,User> = Users;",
+			[]string{"5:12: wrong number of arguments for Vector: got 2, expected 1"},
+		},
+		{
+			"users list:Tuple<User,User> = Users;",
+			[]string{"5:23: User is not a nat expression"},
+		},
+		{
+			"users n:# list:Vector<n> = Users;",
+			[]string{"5:23: n is not a type"},
+		},
+		{
+			"users id:int list:id*[User] = Users;",
+			[]string{"5:19: id is not of type #"},
+		},
+		{
+			"users list:[User] = Users;",
+			[]string{"5:12: repetition without multiplicity must follow a field of type #"},
+		},
+		{
+			"users {t:Type} list:(t int) = Users;",
+			[]string{"5:22: cannot apply type variable t"},
+		},
+		{
+			"---functions---\nping {n:#} query:!n = User;",
+			[]string{"6:18: ! must be followed by a type variable, not n"},
+		},
+		{
+			"---functions---\nping query:!User = User;",
+			[]string{"6:12: ! must be followed by a type variable, not User"},
+		},
+		{
+			"---functions---\ngetTuple = Tuple User;",
+			[]string{"6:12: wrong number of arguments for Tuple: got 1, expected 2"},
+		},
+	}
+
+	var checker Checker
+	for i, tt := range tests {
+		errs := checker.Check(parseString(t, prelude+tt.src))
+
+		var got []string
+		for _, err := range errs {
+			got = append(got, err.Error())
+		}
+		if len(got) != len(tt.errs) {
+			t.Errorf("<%d> %q: got errors %q, expected %q", i, tt.src, got, tt.errs)
+			continue
+		}
+		for j := range got {
+			if got[j] != tt.errs[j] {
+				t.Errorf("<%d> %q: got error %q, expected %q", i, tt.src, got[j], tt.errs[j])
+			}
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package tl
+
+import "fmt"
+
+// A Visitor's Visit method is invoked for each node encountered by Walk.
+// If the result visitor w is not nil, Walk visits each of the children
+// of node with the visitor w, followed by a call of w.Visit(nil).
+type Visitor interface {
+	Visit(node Node) (w Visitor)
+}
+
+// Walk traverses an AST in depth-first order: It starts by calling
+// v.Visit(node); node must not be nil. If the visitor w returned by
+// v.Visit(node) is not nil, Walk is invoked recursively with visitor
+// w for each of the non-nil children of node, followed by a call of
+// w.Visit(nil).
+func Walk(v Visitor, node Node) {
+	if v = v.Visit(node); v == nil {
+		return
+	}
+
+	// walk children
+	// (the order of the cases matches the order
+	// of the corresponding node types in ast.go)
+	switch n := node.(type) {
+	case *Program:
+		walkDeclList(v, n.Constructors)
+		walkDeclList(v, n.Functions)
+		walkDeclList(v, n.Types)
+		for _, c := range n.Comments {
+			Walk(v, c)
+		}
+
+	case *Comment, *Ident:
+		// nothing to do
+
+	case *CombinatorId:
+		Walk(v, n.Id)
+
+	case *FullCombinatorId:
+		Walk(v, n.Id)
+
+	// Expressions
+	case *TypeIdent, *Var, *NatConst:
+		// nothing to do
+
+	case *BareType:
+		Walk(v, n.X)
+
+	case *ParenExpr:
+		Walk(v, n.X)
+
+	case *AppExpr:
+		Walk(v, n.Fun)
+		walkExprList(v, n.Args)
+
+	case *SumExpr:
+		Walk(v, n.X)
+		Walk(v, n.Y)
+
+	case *Repetition:
+		if n.Mult != nil {
+			Walk(v, n.Mult)
+		}
+		walkArgList(v, n.Args)
+
+	// Arguments
+	case *Arg:
+		walkIdentList(v, n.Names)
+		if n.Cond != nil {
+			Walk(v, n.Cond)
+		}
+		Walk(v, n.Type)
+
+	case *OptionalArg:
+		walkIdentList(v, n.Names)
+		Walk(v, n.Type)
+
+	case *CondDef:
+		Walk(v, n.Field)
+		if n.Bit != nil {
+			Walk(v, n.Bit)
+		}
+
+	case *ResultType:
+		Walk(v, n.Name)
+		walkExprList(v, n.Args)
+
+	case *BoxedTypeIdent:
+		// nothing to do
+
+	// Declarations
+	case *CombDecl:
+		Walk(v, n.Id)
+		for _, arg := range n.OptArgs {
+			Walk(v, arg)
+		}
+		walkArgList(v, n.Args)
+		Walk(v, n.Result)
+
+	case *BuiltinCombDecl:
+		Walk(v, n.Id)
+		Walk(v, n.Result)
+
+	case *PartialTypeAppDecl:
+		Walk(v, n.Name)
+		walkExprList(v, n.Args)
+
+	case *PartialCombAppDecl:
+		Walk(v, n.Id)
+		walkExprList(v, n.Args)
+
+	case *FinalDecl:
+		Walk(v, n.Name)
+
+	default:
+		panic(fmt.Sprintf("tl.Walk: unexpected node type %T", n))
+	}
+
+	v.Visit(nil)
+}
+
+func walkDeclList(v Visitor, list []Declaration) {
+	for _, x := range list {
+		Walk(v, x)
+	}
+}
+
+func walkExprList(v Visitor, list []Expr) {
+	for _, x := range list {
+		Walk(v, x)
+	}
+}
+
+func walkArgList(v Visitor, list []*Arg) {
+	for _, x := range list {
+		Walk(v, x)
+	}
+}
+
+func walkIdentList(v Visitor, list []*Ident) {
+	for _, x := range list {
+		Walk(v, x)
+	}
+}
+
+type inspector func(Node) bool
+
+func (f inspector) Visit(node Node) Visitor {
+	if f(node) {
+		return f
+	}
+	return nil
+}
+
+// Inspect traverses an AST in depth-first order: It starts by calling
+// f(node); node must not be nil. If f returns true, Inspect invokes f
+// recursively for each of the non-nil children of node, followed by a
+// call of f(nil).
+func Inspect(node Node, f func(Node) bool) {
+	Walk(inspector(f), node)
+}
@@ -0,0 +1,87 @@
+package tl
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestInspect(t *testing.T) {
+	const src = `
+vector {t:Type} # [t] = Vector t;
+---functions---
+msg flags:# x:flags.2?%(Vector<int>) = Msg;
+`
+
+	parser := NewParser(bytes.NewBufferString(src))
+	program := parser.Parse()
+	if parser.Err() != nil {
+		t.Fatal(parser.Err())
+	}
+
+	var got []string
+	Inspect(program, func(n Node) bool {
+		switch n := n.(type) {
+		case nil, *Program:
+		case *Ident:
+			got = append(got, "Ident "+n.Text())
+		case *TypeIdent:
+			got = append(got, "TypeIdent "+n.Name)
+		case *BoxedTypeIdent:
+			got = append(got, "BoxedTypeIdent "+n.Name)
+		case *Var:
+			got = append(got, "Var "+n.Name)
+		case *NatConst:
+			got = append(got, "NatConst "+n.Value)
+		default:
+			got = append(got, fmt.Sprintf("%T", n)[4:])
+		}
+		return true
+	})
+
+	want := []string{
+		"CombDecl",
+		"FullCombinatorId", "Ident vector",
+		"OptionalArg", "Ident t", "TypeIdent Type",
+		"Arg", "TypeIdent #",
+		"Arg", "Repetition", "Arg", "Var t",
+		"ResultType", "BoxedTypeIdent Vector", "Var t",
+		"CombDecl",
+		"FullCombinatorId", "Ident msg",
+		"Arg", "Ident flags", "TypeIdent #",
+		"Arg", "Ident x", "CondDef", "Ident flags", "NatConst 2",
+		"BareType", "ParenExpr", "AppExpr", "TypeIdent Vector", "TypeIdent int",
+		"ResultType", "BoxedTypeIdent Msg",
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("bad traversal:\ngot  %q\nwant %q", got, want)
+	}
+}
+
+func TestInspect_prune(t *testing.T) {
+	const src = `pair {X:Type} {Y:Type} a:X b:Y = Pair X Y;`
+
+	parser := NewParser(bytes.NewBufferString(src))
+	program := parser.Parse()
+	if parser.Err() != nil {
+		t.Fatal(parser.Err())
+	}
+
+	var vars int
+	Inspect(program, func(n Node) bool {
+		switch n.(type) {
+		case *OptionalArg, *ResultType:
+			// skip type parameters and the result type
+			return false
+		case *Var:
+			vars++
+		}
+		return true
+	})
+
+	if vars != 2 {
+		t.Errorf("got %d variables, expected 2", vars)
+	}
+}